@@ -0,0 +1,283 @@
+package main
+
+import (
+	"chirpy/internal/database"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// Block stops two users from interacting: existing follows in both
+// directions are dropped, neither can follow or mention the other, and
+// their chirps disappear from each other's listings.
+func (cfg *apiConfig) Block(w http.ResponseWriter, r *http.Request) {
+	userUUID, err := cfg.authenticate(r)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 401, res)
+		return
+	}
+
+	target, err := cfg.dbQueries.GetUserByHandle(r.Context(), r.PathValue("handle"))
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 404, res)
+		return
+	}
+
+	if target.ID == userUUID {
+		res := `{"error":"you can't block yourself"}`
+		formJsonResponse(w, 400, res)
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	err = qtx.BlockUser(r.Context(), database.BlockUserParams{
+		BlockerID: userUUID,
+		BlockedID: target.ID,
+	})
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	err = qtx.RemoveFollowsBetween(r.Context(), database.RemoveFollowsBetweenParams{
+		UserA: userUUID,
+		UserB: target.ID,
+	})
+	if err == nil {
+		// A pending request would otherwise turn back into a follow if
+		// it were approved later.
+		err = qtx.RemoveFollowRequestsBetween(r.Context(), database.RemoveFollowRequestsBetweenParams{
+			UserA: userUUID,
+			UserB: target.ID,
+		})
+	}
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	err = cfg.timelines.FollowChanged(r.Context(), userUUID, target.ID, false)
+	if err == nil {
+		err = cfg.timelines.FollowChanged(r.Context(), target.ID, userUUID, false)
+	}
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) Unblock(w http.ResponseWriter, r *http.Request) {
+	userUUID, err := cfg.authenticate(r)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 401, res)
+		return
+	}
+
+	target, err := cfg.dbQueries.GetUserByHandle(r.Context(), r.PathValue("handle"))
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 404, res)
+		return
+	}
+
+	err = cfg.dbQueries.UnblockUser(r.Context(), database.UnblockUserParams{
+		BlockerID: userUUID,
+		BlockedID: target.ID,
+	})
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) GetBlocks(w http.ResponseWriter, r *http.Request) {
+	userUUID, err := cfg.authenticate(r)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 401, res)
+		return
+	}
+
+	after, limit, err := parsePage(r, newestFirst)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 400, res)
+		return
+	}
+
+	rows, err := cfg.dbQueries.GetBlockedUsers(r.Context(), database.GetBlockedUsersParams{
+		UserID:          userUUID,
+		BeforeCreatedAt: after.CreatedAt,
+		BeforeID:        after.ID,
+		PageSize:        limit,
+	})
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	profiles := []Profile{}
+	for _, row := range rows {
+		profiles = append(profiles, Profile{
+			ID:          row.ID,
+			CreatedAt:   row.CreatedAt,
+			Handle:      row.Handle,
+			DisplayName: row.DisplayName,
+			Bio:         row.Bio,
+			AvatarURL:   row.AvatarUrl,
+			Private:     row.Private,
+		})
+	}
+	if len(rows) == int(limit) {
+		last := rows[len(rows)-1]
+		setNextPage(w, r, pageCursor{CreatedAt: last.BlockedAt, ID: last.ID})
+	}
+
+	jsr, err := json.Marshal(profiles)
+	if err != nil {
+		panic(err)
+	}
+	respondWithJson(w, 200, jsr)
+}
+
+// Mute only hides the muted user's chirps from the muter's listings. Unlike
+// a block, the muted user isn't told and can still interact.
+func (cfg *apiConfig) Mute(w http.ResponseWriter, r *http.Request) {
+	userUUID, err := cfg.authenticate(r)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 401, res)
+		return
+	}
+
+	target, err := cfg.dbQueries.GetUserByHandle(r.Context(), r.PathValue("handle"))
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 404, res)
+		return
+	}
+
+	if target.ID == userUUID {
+		res := `{"error":"you can't mute yourself"}`
+		formJsonResponse(w, 400, res)
+		return
+	}
+
+	err = cfg.dbQueries.MuteUser(r.Context(), database.MuteUserParams{
+		MuterID: userUUID,
+		MutedID: target.ID,
+	})
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) Unmute(w http.ResponseWriter, r *http.Request) {
+	userUUID, err := cfg.authenticate(r)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 401, res)
+		return
+	}
+
+	target, err := cfg.dbQueries.GetUserByHandle(r.Context(), r.PathValue("handle"))
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 404, res)
+		return
+	}
+
+	err = cfg.dbQueries.UnmuteUser(r.Context(), database.UnmuteUserParams{
+		MuterID: userUUID,
+		MutedID: target.ID,
+	})
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) GetMutes(w http.ResponseWriter, r *http.Request) {
+	userUUID, err := cfg.authenticate(r)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 401, res)
+		return
+	}
+
+	after, limit, err := parsePage(r, newestFirst)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 400, res)
+		return
+	}
+
+	rows, err := cfg.dbQueries.GetMutedUsers(r.Context(), database.GetMutedUsersParams{
+		UserID:          userUUID,
+		BeforeCreatedAt: after.CreatedAt,
+		BeforeID:        after.ID,
+		PageSize:        limit,
+	})
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	profiles := []Profile{}
+	for _, row := range rows {
+		profiles = append(profiles, Profile{
+			ID:          row.ID,
+			CreatedAt:   row.CreatedAt,
+			Handle:      row.Handle,
+			DisplayName: row.DisplayName,
+			Bio:         row.Bio,
+			AvatarURL:   row.AvatarUrl,
+			Private:     row.Private,
+		})
+	}
+	if len(rows) == int(limit) {
+		last := rows[len(rows)-1]
+		setNextPage(w, r, pageCursor{CreatedAt: last.MutedAt, ID: last.ID})
+	}
+
+	jsr, err := json.Marshal(profiles)
+	if err != nil {
+		panic(err)
+	}
+	respondWithJson(w, 200, jsr)
+}
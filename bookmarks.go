@@ -0,0 +1,413 @@
+package main
+
+import (
+	"chirpy/internal/database"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+const maxCollectionNameLength = 50
+
+func checkCollectionName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", errors.New("name can't be empty")
+	}
+	if utf8.RuneCountInString(name) > maxCollectionNameLength {
+		return "", fmt.Errorf("name must be at most %d characters", maxCollectionNameLength)
+	}
+	return name, nil
+}
+
+func (cfg *apiConfig) CreateCollection(w http.ResponseWriter, r *http.Request) {
+	type cred struct {
+		Name string `json:"name"`
+	}
+
+	userUUID, err := cfg.authenticate(r)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 401, res)
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := cred{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 400, res)
+		return
+	}
+
+	name, err := checkCollectionName(params.Name)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 400, res)
+		return
+	}
+
+	collection, err := cfg.dbQueries.CreateCollection(r.Context(), database.CreateCollectionParams{
+		UserID: userUUID,
+		Name:   name,
+	})
+	if isUniqueViolation(err) {
+		formJsonResponse(w, 409, `{"error":"you already have a collection with that name"}`)
+		return
+	}
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	jsr, err := json.Marshal(Collection{
+		ID:        collection.ID,
+		CreatedAt: collection.CreatedAt,
+		Name:      collection.Name,
+	})
+	if err != nil {
+		panic(err)
+	}
+	respondWithJson(w, 201, jsr)
+}
+
+func (cfg *apiConfig) GetCollections(w http.ResponseWriter, r *http.Request) {
+	userUUID, err := cfg.authenticate(r)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 401, res)
+		return
+	}
+
+	rows, err := cfg.dbQueries.GetCollections(r.Context(), userUUID)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	collections := []Collection{}
+	for _, row := range rows {
+		collections = append(collections, Collection{
+			ID:             row.ID,
+			CreatedAt:      row.CreatedAt,
+			Name:           row.Name,
+			BookmarksCount: row.BookmarksCount,
+		})
+	}
+
+	jsr, err := json.Marshal(collections)
+	if err != nil {
+		panic(err)
+	}
+	respondWithJson(w, 200, jsr)
+}
+
+func (cfg *apiConfig) RenameCollection(w http.ResponseWriter, r *http.Request) {
+	type cred struct {
+		Name string `json:"name"`
+	}
+
+	userUUID, err := cfg.authenticate(r)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 401, res)
+		return
+	}
+
+	collectionID, err := uuid.Parse(r.PathValue("collectionid"))
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 400, res)
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := cred{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 400, res)
+		return
+	}
+
+	name, err := checkCollectionName(params.Name)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 400, res)
+		return
+	}
+
+	collection, err := cfg.dbQueries.RenameCollection(r.Context(), database.RenameCollectionParams{
+		ID:     collectionID,
+		UserID: userUUID,
+		Name:   name,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		formJsonResponse(w, 404, `{"error":"collection not found"}`)
+		return
+	}
+	if isUniqueViolation(err) {
+		formJsonResponse(w, 409, `{"error":"you already have a collection with that name"}`)
+		return
+	}
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	jsr, err := json.Marshal(Collection{
+		ID:        collection.ID,
+		CreatedAt: collection.CreatedAt,
+		Name:      collection.Name,
+	})
+	if err != nil {
+		panic(err)
+	}
+	respondWithJson(w, 200, jsr)
+}
+
+// DeleteCollection removes a collection but keeps its bookmarks, which go
+// back to being uncollected.
+func (cfg *apiConfig) DeleteCollection(w http.ResponseWriter, r *http.Request) {
+	userUUID, err := cfg.authenticate(r)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 401, res)
+		return
+	}
+
+	collectionID, err := uuid.Parse(r.PathValue("collectionid"))
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 400, res)
+		return
+	}
+
+	n, err := cfg.dbQueries.DeleteCollection(r.Context(), database.DeleteCollectionParams{
+		ID:     collectionID,
+		UserID: userUUID,
+	})
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+	if n == 0 {
+		formJsonResponse(w, 404, `{"error":"collection not found"}`)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+// Bookmark saves a chirp the caller can see, optionally into one of their
+// collections. Bookmarking it again moves it to another collection, or
+// out of any with no collection_id.
+func (cfg *apiConfig) Bookmark(w http.ResponseWriter, r *http.Request) {
+	type cred struct {
+		CollectionID *uuid.UUID `json:"collection_id"`
+	}
+
+	userUUID, err := cfg.authenticate(r)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 401, res)
+		return
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpid"))
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 400, res)
+		return
+	}
+
+	// The body is optional.
+	decoder := json.NewDecoder(r.Body)
+	params := cred{}
+	err = decoder.Decode(&params)
+	if err != nil && !errors.Is(err, io.EOF) {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 400, res)
+		return
+	}
+
+	_, err = cfg.dbQueries.GetChirp(r.Context(), database.GetChirpParams{
+		ID:       chirpID,
+		ViewerID: userUUID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		formJsonResponse(w, 404, `{"error":"chirp not found"}`)
+		return
+	}
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	var collectionID uuid.NullUUID
+	if params.CollectionID != nil {
+		_, err = cfg.dbQueries.GetCollection(r.Context(), database.GetCollectionParams{
+			ID:     *params.CollectionID,
+			UserID: userUUID,
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			formJsonResponse(w, 404, `{"error":"collection not found"}`)
+			return
+		}
+		if err != nil {
+			res := fmt.Sprintf(`{"error":"%v"}`, err)
+			formJsonResponse(w, 500, res)
+			return
+		}
+		collectionID = uuid.NullUUID{UUID: *params.CollectionID, Valid: true}
+	}
+
+	err = cfg.dbQueries.UpsertBookmark(r.Context(), database.UpsertBookmarkParams{
+		UserID:       userUUID,
+		ChirpID:      chirpID,
+		CollectionID: collectionID,
+	})
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) Unbookmark(w http.ResponseWriter, r *http.Request) {
+	userUUID, err := cfg.authenticate(r)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 401, res)
+		return
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpid"))
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 400, res)
+		return
+	}
+
+	n, err := cfg.dbQueries.DeleteBookmark(r.Context(), database.DeleteBookmarkParams{
+		UserID:  userUUID,
+		ChirpID: chirpID,
+	})
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+	if n == 0 {
+		formJsonResponse(w, 404, `{"error":"chirp is not bookmarked"}`)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+// GetBookmarks lists the caller's bookmarks newest first, all of them or
+// those in ?collection_id=. A bookmarked chirp that has since been deleted,
+// or that the caller can no longer see, is listed as a tombstone with a
+// null chirp rather than dropped, so pages keep their size.
+func (cfg *apiConfig) GetBookmarks(w http.ResponseWriter, r *http.Request) {
+	userUUID, err := cfg.authenticate(r)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 401, res)
+		return
+	}
+
+	before, limit, err := parsePage(r, newestFirst)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 400, res)
+		return
+	}
+
+	params := database.GetBookmarksParams{
+		UserID:          userUUID,
+		BeforeCreatedAt: before.CreatedAt,
+		BeforeID:        before.ID,
+		PageSize:        limit,
+	}
+	if s := r.URL.Query().Get("collection_id"); s != "" {
+		params.CollectionID.UUID, err = uuid.Parse(s)
+		if err != nil {
+			res := fmt.Sprintf(`{"error":"%v"}`, err)
+			formJsonResponse(w, 400, res)
+			return
+		}
+		params.CollectionID.Valid = true
+	}
+
+	rows, err := cfg.dbQueries.GetBookmarks(r.Context(), params)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	ids := []uuid.UUID{}
+	for _, b := range rows {
+		ids = append(ids, b.ChirpID)
+	}
+	dbChirps, err := cfg.dbQueries.GetChirpsByIDs(r.Context(), database.GetChirpsByIDsParams{
+		Ids:      ids,
+		ViewerID: userUUID,
+	})
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+	chirps := []Chirp{}
+	for _, c := range dbChirps {
+		chirps = append(chirps, mapChirp(c))
+	}
+	err = cfg.attachEntities(r.Context(), chirps, userUUID)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+	byID := map[uuid.UUID]*Chirp{}
+	for i := range chirps {
+		byID[chirps[i].ID] = &chirps[i]
+	}
+
+	bookmarks := []Bookmark{}
+	for _, b := range rows {
+		bookmark := Bookmark{
+			ChirpID:   b.ChirpID,
+			CreatedAt: b.CreatedAt,
+			Chirp:     byID[b.ChirpID],
+		}
+		if b.CollectionID.Valid {
+			bookmark.CollectionID = &b.CollectionID.UUID
+		}
+		bookmarks = append(bookmarks, bookmark)
+	}
+	if len(rows) == int(limit) {
+		last := rows[len(rows)-1]
+		setNextPage(w, r, pageCursor{CreatedAt: last.CreatedAt, ID: last.ChirpID})
+	}
+
+	jsr, err := json.Marshal(bookmarks)
+	if err != nil {
+		panic(err)
+	}
+	respondWithJson(w, 200, jsr)
+}
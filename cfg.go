@@ -1,12 +1,23 @@
 package main
 
 import (
+	"chirpy/internal/activitypub"
 	"chirpy/internal/auth"
 	"chirpy/internal/database"
+	"chirpy/internal/httpcache"
+	"chirpy/internal/linkpreview"
+	"chirpy/internal/pubsub"
+	"chirpy/internal/ratelimit"
+	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
+	"math"
+	"net"
 	"net/http"
-	"sort"
+	"strconv"
 	"sync/atomic"
 	"time"
 
@@ -14,10 +25,23 @@ import (
 )
 
 type apiConfig struct {
-	fileserverHits atomic.Int32
-	dbQueries      *database.Queries
-	Platform       string
-	Secret         string
+	fileserverHits     atomic.Int32
+	db                 *sql.DB
+	dbQueries          *database.Queries
+	timelines          timelineStore
+	searchLimiter      *ratelimit.Limiter
+	trends             trendsConfig
+	blobs              BlobStore
+	previews           *linkpreview.Fetcher
+	federation         *activitypub.Client
+	publicURL          string
+	restoreWindow      time.Duration
+	events             eventBus
+	chirpStream        *pubsub.Broker[database.ChirpEvent]
+	notificationStream *pubsub.Broker[database.Notification]
+	eventChirps        *chirpEventCache
+	Platform           string
+	Secret             string
 }
 
 func (cfg *apiConfig) mwMetricsInc(next http.Handler) http.Handler {
@@ -27,6 +51,49 @@ func (cfg *apiConfig) mwMetricsInc(next http.Handler) http.Handler {
 	})
 }
 
+// rateLimit turns requests away with 429 once the caller has used up their
+// allowance on limiter. Signed-in users are limited by account, everyone
+// else by address.
+func (cfg *apiConfig) rateLimit(limiter *ratelimit.Limiter, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			key = r.RemoteAddr
+		}
+		if userUUID, err := cfg.authenticate(r); err == nil {
+			key = userUUID.String()
+		}
+
+		ok, wait := limiter.Allow(key)
+		if !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			res := `{"error":"too many requests"}`
+			formJsonResponse(w, 429, res)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// authenticate returns the user the request's bearer JWT was issued to.
+func (cfg *apiConfig) authenticate(r *http.Request) (uuid.UUID, error) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return auth.ValidateJWT(token, cfg.Secret)
+}
+
+// viewer is like authenticate for routes that also serve anonymous
+// requests, which get uuid.Nil. A token that is sent but invalid is still
+// an error rather than a silent downgrade to anonymous.
+func (cfg *apiConfig) viewer(r *http.Request) (uuid.UUID, error) {
+	if r.Header.Get("Authorization") == "" {
+		return uuid.Nil, nil
+	}
+	return cfg.authenticate(r)
+}
+
 func (cfg *apiConfig) metrics(w http.ResponseWriter, r *http.Request) {
 	count := int(cfg.fileserverHits.Load())
 	hits := fmt.Sprintf(`<html>
@@ -53,6 +120,7 @@ func (cfg *apiConfig) createUser(w http.ResponseWriter, r *http.Request) {
 	type cred struct {
 		Password string
 		Email    string
+		Handle   string
 	}
 
 	decoder := json.NewDecoder(r.Body)
@@ -64,6 +132,16 @@ func (cfg *apiConfig) createUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if creds.Handle == "" {
+		creds.Handle = defaultHandle()
+	}
+	status, err := checkHandle(r.Context(), cfg.dbQueries, creds.Handle, uuid.Nil)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, status, res)
+		return
+	}
+
 	hash, err := auth.HashPassword(creds.Password)
 	if err != nil {
 		res := fmt.Sprintf(`{"error":"%v"}`, err)
@@ -74,9 +152,15 @@ func (cfg *apiConfig) createUser(w http.ResponseWriter, r *http.Request) {
 	params := database.CreateUserParams{
 		Email:          creds.Email,
 		HashedPassword: hash,
+		Handle:         creds.Handle,
 	}
 
 	dbUser, err := cfg.dbQueries.CreateUser(r.Context(), params)
+	if isUniqueViolation(err) {
+		res := fmt.Sprintf(`{"error":"%v"}`, errHandleTaken)
+		formJsonResponse(w, 409, res)
+		return
+	}
 	if err != nil {
 		res := fmt.Sprintf(`{"error":"%v"}`, err)
 		formJsonResponse(w, 500, res)
@@ -84,10 +168,15 @@ func (cfg *apiConfig) createUser(w http.ResponseWriter, r *http.Request) {
 	}
 
 	user := User{
-		ID:        dbUser.ID,
-		CreatedAt: dbUser.CreatedAt,
-		UpdatedAt: dbUser.UpdatedAt,
-		Email:     dbUser.Email,
+		ID:          dbUser.ID,
+		CreatedAt:   dbUser.CreatedAt,
+		UpdatedAt:   dbUser.UpdatedAt,
+		Email:       dbUser.Email,
+		Handle:      dbUser.Handle,
+		DisplayName: dbUser.DisplayName,
+		Bio:         dbUser.Bio,
+		AvatarURL:   dbUser.AvatarUrl,
+		Private:     dbUser.Private,
 	}
 
 	jsr, err := json.Marshal(user)
@@ -142,12 +231,17 @@ func (cfg *apiConfig) Login(w http.ResponseWriter, r *http.Request) {
 	cfg.dbQueries.CreateRefreshToken(r.Context(), refparams)
 
 	user := User{
-		ID:        dbUser.ID,
-		CreatedAt: dbUser.CreatedAt,
-		UpdatedAt: dbUser.UpdatedAt,
-		Email:     dbUser.Email,
-		Token:     token,
-		Refresh:   reftok,
+		ID:          dbUser.ID,
+		CreatedAt:   dbUser.CreatedAt,
+		UpdatedAt:   dbUser.UpdatedAt,
+		Email:       dbUser.Email,
+		Handle:      dbUser.Handle,
+		DisplayName: dbUser.DisplayName,
+		Bio:         dbUser.Bio,
+		AvatarURL:   dbUser.AvatarUrl,
+		Private:     dbUser.Private,
+		Token:       token,
+		Refresh:     reftok,
 	}
 
 	jsr, err := json.Marshal(user)
@@ -157,10 +251,78 @@ func (cfg *apiConfig) Login(w http.ResponseWriter, r *http.Request) {
 	respondWithJson(w, 200, jsr)
 }
 
+// errNoMentions rejects a mentioned-only chirp that mentions nobody, which
+// only its author could ever see.
+var errNoMentions = errors.New("a mentioned-only chirp must mention at least one user")
+
+// publishedChirp is a chirp that has been stored along with everything
+// that has to be announced once its transaction commits.
+type publishedChirp struct {
+	chirp         database.Chirp
+	notifications []database.Notification
+	event         database.ChirpEvent
+}
+
+// insertChirp stores a new chirp with its mentions, hashtags, links and
+// created event. Whoever calls it passes the result to announceChirp after
+// committing.
+func insertChirp(ctx context.Context, qtx *database.Queries, par database.CreateChirpParams) (publishedChirp, error) {
+	dbChirp, err := qtx.CreateChirp(ctx, par)
+	if err != nil {
+		return publishedChirp{}, err
+	}
+
+	notifications, err := recordMentions(ctx, qtx, dbChirp)
+	if err != nil {
+		return publishedChirp{}, err
+	}
+	if dbChirp.Visibility == visibilityMentioned && len(notifications) == 0 {
+		return publishedChirp{}, errNoMentions
+	}
+
+	err = recordHashtags(ctx, qtx, dbChirp)
+	if err != nil {
+		return publishedChirp{}, err
+	}
+
+	err = recordLinks(ctx, qtx, dbChirp)
+	if err != nil {
+		return publishedChirp{}, err
+	}
+
+	event, err := qtx.CreateChirpEvent(ctx, database.CreateChirpEventParams{
+		Kind:    chirpEventCreated,
+		ChirpID: dbChirp.ID,
+		UserID:  dbChirp.UserID,
+	})
+	if err != nil {
+		return publishedChirp{}, err
+	}
+	return publishedChirp{chirp: dbChirp, notifications: notifications, event: event}, nil
+}
+
+// announceChirp tells streams, notified users, remote followers and
+// timelines about a chirp insertChirp stored.
+func (cfg *apiConfig) announceChirp(ctx context.Context, p publishedChirp) error {
+	cfg.events.ChirpEventPublished(p.event)
+	for _, n := range p.notifications {
+		cfg.events.NotificationPublished(n)
+	}
+	err := cfg.federateChirp(ctx, p.chirp, activityCreate)
+	if err != nil {
+		log.Printf("federation: %v", err)
+	}
+	return cfg.timelines.ChirpCreated(ctx, p.chirp)
+}
+
 func (cfg *apiConfig) createChirp(w http.ResponseWriter, r *http.Request) {
 	type cred struct {
-		Body    string `json:"body"`
-		User_id string `json:"user_id"`
+		Body           string      `json:"body"`
+		User_id        string      `json:"user_id"`
+		Visibility     string      `json:"visibility"`
+		ContentWarning string      `json:"content_warning"`
+		MediaIDs       []uuid.UUID `json:"media_ids"`
+		Poll           *pollParams `json:"poll"`
 	}
 
 	decoder := json.NewDecoder(r.Body)
@@ -186,18 +348,102 @@ func (cfg *apiConfig) createChirp(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	visibility, contentWarning, err := validateChirpOptions(params.Body, params.Visibility, params.ContentWarning)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 400, res)
+		return
+	}
+
+	err = checkMediaIDs(params.MediaIDs)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 400, res)
+		return
+	}
+
+	var pollOptions []string
+	var pollDuration time.Duration
+	if params.Poll != nil {
+		pollOptions, pollDuration, err = checkPoll(*params.Poll)
+		if err != nil {
+			res := fmt.Sprintf(`{"error":"%v"}`, err)
+			formJsonResponse(w, 400, res)
+			return
+		}
+	}
+
 	var par database.CreateChirpParams
 	par.Body = params.Body
 	par.UserID = userUUID
+	par.Visibility = visibility
+	par.ContentWarning = contentWarning
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	published, err := insertChirp(r.Context(), qtx, par)
+	if errors.Is(err, errNoMentions) {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 400, res)
+		return
+	}
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+	dbChirp := published.chirp
+
+	err = attachMedia(r.Context(), qtx, dbChirp, params.MediaIDs)
+	if errors.Is(err, errMediaUnavailable) {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 400, res)
+		return
+	}
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	if params.Poll != nil {
+		err = createPoll(r.Context(), qtx, dbChirp.ID, pollOptions, params.Poll.Multiple, pollDuration)
+		if err != nil {
+			res := fmt.Sprintf(`{"error":"%v"}`, err)
+			formJsonResponse(w, 500, res)
+			return
+		}
+	}
 
-	dbChirp, err := cfg.dbQueries.CreateChirp(r.Context(), par)
+	err = tx.Commit()
 	if err != nil {
 		res := fmt.Sprintf(`{"error":"%v"}`, err)
 		formJsonResponse(w, 500, res)
 		return
 	}
 
-	chirp := mapChirp(dbChirp)
+	err = cfg.announceChirp(r.Context(), published)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	chirps := []Chirp{mapChirp(dbChirp)}
+	err = cfg.attachEntities(r.Context(), chirps, userUUID)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+	chirp := chirps[0]
 
 	jsr, err := json.Marshal(chirp)
 	if err != nil {
@@ -207,24 +453,87 @@ func (cfg *apiConfig) createChirp(w http.ResponseWriter, r *http.Request) {
 }
 
 func (cfg *apiConfig) GetChirps(w http.ResponseWriter, r *http.Request) {
-	dbChirps, err := cfg.dbQueries.GetChirps(r.Context())
+	viewerUUID, err := cfg.viewer(r)
 	if err != nil {
 		res := fmt.Sprintf(`{"error":"%v"}`, err)
-		formJsonResponse(w, 500, res)
+		formJsonResponse(w, 401, res)
+		return
+	}
+
+	after, limit, err := parsePage(r, oldestFirst)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 400, res)
 		return
 	}
+	// This list predates pagination, so clients that don't ask for a page
+	// still get every chirp.
+	if !r.URL.Query().Has("cursor") && !r.URL.Query().Has("limit") {
+		limit = math.MaxInt32
+	}
+
+	var authorID uuid.NullUUID
+	if s := r.URL.Query().Get("author_id"); s != "" {
+		authorID.UUID, err = uuid.Parse(s)
+		if err != nil {
+			res := fmt.Sprintf(`{"error":"%v"}`, err)
+			formJsonResponse(w, 400, res)
+			return
+		}
+		authorID.Valid = true
+	}
+
+	// An author's pinned chirps come first, on the first page only, and
+	// aren't repeated further down.
 	chirps := []Chirp{}
+	if authorID.Valid && !r.URL.Query().Has("cursor") {
+		pinned, err := cfg.dbQueries.GetPinnedChirps(r.Context(), database.GetPinnedChirpsParams{
+			AuthorID: authorID.UUID,
+			ViewerID: viewerUUID,
+		})
+		if err != nil {
+			res := fmt.Sprintf(`{"error":"%v"}`, err)
+			formJsonResponse(w, 500, res)
+			return
+		}
+		for _, c := range pinned {
+			chirps = append(chirps, mapChirp(c))
+		}
+	}
+
+	dbChirps, err := cfg.dbQueries.GetChirps(r.Context(), database.GetChirpsParams{
+		ViewerID:       viewerUUID,
+		AuthorID:       authorID,
+		AfterCreatedAt: after.CreatedAt,
+		AfterID:        after.ID,
+		PageSize:       limit,
+	})
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
 	for _, c := range dbChirps {
 		chirps = append(chirps, mapChirp(c))
 	}
-	sort.Slice(chirps, func(i, j int) bool {
-		return chirps[i].CreatedAt.Before(chirps[j].CreatedAt)
-	})
+	if len(dbChirps) == int(limit) {
+		last := dbChirps[len(dbChirps)-1]
+		setNextPage(w, r, pageCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+	err = cfg.attachEntities(r.Context(), chirps, viewerUUID)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
 	jsr, err := json.Marshal(chirps)
 	if err != nil {
 		panic(err)
 	}
-	respondWithJson(w, 200, jsr)
+	// A list has no Last-Modified: chirps leaving it through a delete,
+	// block, mute or visibility change don't move any updated_at, so
+	// only the ETag can tell the client its copy is stale.
+	httpcache.Write(w, r, "application/json", jsr, time.Time{})
 }
 
 func (cfg *apiConfig) GetChirpByID(w http.ResponseWriter, r *http.Request) {
@@ -236,19 +545,39 @@ func (cfg *apiConfig) GetChirpByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	dbChirp, err := cfg.dbQueries.GetChirp(r.Context(), chirpID)
+	viewerUUID, err := cfg.viewer(r)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 401, res)
+		return
+	}
+
+	dbChirp, err := cfg.dbQueries.GetChirp(r.Context(), database.GetChirpParams{
+		ID:       chirpID,
+		ViewerID: viewerUUID,
+	})
 	if err != nil {
 		res := fmt.Sprintf(`{"error":"%v"}`, err)
 		formJsonResponse(w, 404, res)
 		return
 	}
-	chirp := mapChirp(dbChirp)
+	chirps := []Chirp{mapChirp(dbChirp)}
+	err = cfg.attachEntities(r.Context(), chirps, viewerUUID)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+	chirp := chirps[0]
 
 	jsr, err := json.Marshal(chirp)
 	if err != nil {
 		panic(err)
 	}
-	respondWithJson(w, 200, jsr)
+	// No Last-Modified either: poll counts, link previews and the pinned
+	// flag change the body without touching updated_at, so the ETag, a
+	// hash of the body, is the only validator that notices.
+	httpcache.Write(w, r, "application/json", jsr, time.Time{})
 }
 
 func (cfg *apiConfig) Refresh(w http.ResponseWriter, r *http.Request) {
@@ -393,15 +722,98 @@ func (cfg *apiConfig) DeleteChirp(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if owner == userUUID {
-		err = cfg.dbQueries.DeleteChirp(r.Context(), chirpID)
+	moderator := false
+	if owner != userUUID {
+		moderator, err = cfg.dbQueries.IsModerator(r.Context(), userUUID)
 		if err != nil {
 			res := fmt.Sprintf(`{"error":"%v"}`, err)
 			formJsonResponse(w, 500, res)
 			return
 		}
-		w.WriteHeader(204)
-	} else {
-		w.WriteHeader(403)
+		if !moderator {
+			w.WriteHeader(403)
+			return
+		}
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	// The chirp is only hidden for now; runChirpPurger deletes it for good
+	// once it can no longer be restored.
+	n, err := qtx.SoftDeleteChirp(r.Context(), database.SoftDeleteChirpParams{
+		DeletedBy: uuid.NullUUID{UUID: userUUID, Valid: true},
+		ID:        chirpID,
+	})
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+	if n == 0 {
+		formJsonResponse(w, 404, `{"error":"chirp not found"}`)
+		return
+	}
+
+	// A deleted chirp gives up its pin, and stays unpinned if restored.
+	n, err = qtx.UnpinChirp(r.Context(), database.UnpinChirpParams{
+		UserID:  owner,
+		ChirpID: chirpID,
+	})
+	if err == nil && n > 0 {
+		err = qtx.CompactPinnedChirps(r.Context(), owner)
+	}
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	if moderator {
+		err = qtx.CreateModerationAction(r.Context(), database.CreateModerationActionParams{
+			ModeratorID: uuid.NullUUID{UUID: userUUID, Valid: true},
+			Action:      moderationDeleteChirp,
+			ChirpID:     chirpID,
+			AuthorID:    owner,
+		})
+		if err != nil {
+			res := fmt.Sprintf(`{"error":"%v"}`, err)
+			formJsonResponse(w, 500, res)
+			return
+		}
+	}
+
+	event, err := qtx.CreateChirpEvent(r.Context(), database.CreateChirpEventParams{
+		Kind:    chirpEventDeleted,
+		ChirpID: chirpID,
+		UserID:  owner,
+	})
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
 	}
+
+	err = tx.Commit()
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+	cfg.events.ChirpEventPublished(event)
+
+	deleted, err := cfg.dbQueries.GetDeletedChirp(r.Context(), chirpID)
+	if err == nil {
+		err = cfg.federateChirp(r.Context(), deleted, activityDelete)
+	}
+	if err != nil {
+		log.Printf("federation: %v", err)
+	}
+	w.WriteHeader(204)
 }
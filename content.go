@@ -0,0 +1,17 @@
+package main
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+const maxMessageLength = 1000
+
+// checkBody holds a direct message to its length limit. Messages are
+// otherwise stored as written, the same as chirps.
+func checkBody(body string, maxLength int) error {
+	if utf8.RuneCountInString(body) > maxLength {
+		return fmt.Errorf("body must be at most %d characters", maxLength)
+	}
+	return nil
+}
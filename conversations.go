@@ -0,0 +1,500 @@
+package main
+
+import (
+	"chirpy/internal/database"
+	"chirpy/internal/entities"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// maxConversationMembers caps group conversations, counting whoever starts
+// them.
+const maxConversationMembers = 10
+
+// directKey names the one conversation two users have with just each
+// other, whichever of them starts it.
+func directKey(a, b uuid.UUID) sql.NullString {
+	ids := []string{a.String(), b.String()}
+	slices.Sort(ids)
+	return sql.NullString{String: strings.Join(ids, ":"), Valid: true}
+}
+
+// conversationMembers loads the members of each conversation, oldest member
+// first.
+func (cfg *apiConfig) conversationMembers(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID][]ConversationMember, error) {
+	rows, err := cfg.dbQueries.GetConversationMembers(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	members := map[uuid.UUID][]ConversationMember{}
+	for _, row := range rows {
+		members[row.ConversationID] = append(members[row.ConversationID], ConversationMember{
+			Profile:    mapProfile(row.User),
+			LastReadAt: row.LastReadAt,
+		})
+	}
+	return members, nil
+}
+
+// memberConversation loads the conversation in the path, as long as the
+// user is in it. Anyone else gets a 404 so they can't probe for ids.
+func (cfg *apiConfig) memberConversation(r *http.Request, userID uuid.UUID) (database.Conversation, int, error) {
+	conversationID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		return database.Conversation{}, 400, err
+	}
+	conversation, err := cfg.dbQueries.GetConversationForMember(r.Context(), database.GetConversationForMemberParams{
+		ID:     conversationID,
+		UserID: userID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return conversation, 404, errors.New("conversation not found")
+	}
+	if err != nil {
+		return conversation, 500, err
+	}
+	return conversation, 0, nil
+}
+
+func mapMessage(m database.Message, members []ConversationMember) Message {
+	message := Message{
+		ID:             m.ID,
+		CreatedAt:      m.CreatedAt,
+		ConversationID: m.ConversationID,
+		SenderID:       m.SenderID,
+		Body:           m.Body,
+		ReadBy:         []uuid.UUID{},
+	}
+	for _, member := range members {
+		if member.ID != m.SenderID && !member.LastReadAt.Before(m.CreatedAt) {
+			message.ReadBy = append(message.ReadBy, member.ID)
+		}
+	}
+	return message
+}
+
+// CreateConversation starts a conversation with the users named in the
+// body. Starting a one-to-one conversation that already exists returns the
+// existing one. A group can't be started with two members who have a
+// block between them.
+func (cfg *apiConfig) CreateConversation(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Handles []string `json:"handles"`
+	}
+
+	userUUID, err := cfg.authenticate(r)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 401, res)
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 400, res)
+		return
+	}
+
+	handles := []string{}
+	for _, handle := range params.Handles {
+		handle = entities.NormalizeHandle(strings.TrimPrefix(handle, "@"))
+		if !slices.Contains(handles, handle) {
+			handles = append(handles, handle)
+		}
+	}
+	if len(handles) == 0 || len(handles) >= maxConversationMembers {
+		res := fmt.Sprintf(`{"error":"a conversation needs between 1 and %d other members"}`, maxConversationMembers-1)
+		formJsonResponse(w, 400, res)
+		return
+	}
+
+	// Users with a block between them and the requester aren't returned,
+	// so they come out the same as handles nobody has.
+	users, err := cfg.dbQueries.GetUsersByHandles(r.Context(), database.GetUsersByHandlesParams{
+		Handles:  handles,
+		AuthorID: userUUID,
+	})
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+	memberIDs := []uuid.UUID{}
+	for _, handle := range handles {
+		i := slices.IndexFunc(users, func(u database.GetUsersByHandlesRow) bool {
+			return entities.NormalizeHandle(u.Handle) == handle
+		})
+		if i < 0 {
+			res := fmt.Sprintf(`{"error":"user %s not found"}`, handle)
+			formJsonResponse(w, 404, res)
+			return
+		}
+		if users[i].ID == userUUID {
+			res := `{"error":"you can't start a conversation with yourself"}`
+			formJsonResponse(w, 400, res)
+			return
+		}
+		memberIDs = append(memberIDs, users[i].ID)
+	}
+
+	blocked, err := cfg.dbQueries.BlockedAmong(r.Context(), memberIDs)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+	if blocked {
+		res := `{"error":"some of these users can't be in a conversation together"}`
+		formJsonResponse(w, 403, res)
+		return
+	}
+
+	var key sql.NullString
+	if len(memberIDs) == 1 {
+		key = directKey(userUUID, memberIDs[0])
+		existing, err := cfg.dbQueries.GetDirectConversation(r.Context(), key)
+		if err == nil {
+			cfg.respondWithConversation(w, r, 200, existing)
+			return
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			res := fmt.Sprintf(`{"error":"%v"}`, err)
+			formJsonResponse(w, 500, res)
+			return
+		}
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	conversation, err := qtx.CreateConversation(r.Context(), key)
+	if isUniqueViolation(err) {
+		// The other user started it at the same moment.
+		tx.Rollback()
+		existing, err := cfg.dbQueries.GetDirectConversation(r.Context(), key)
+		if err != nil {
+			res := fmt.Sprintf(`{"error":"%v"}`, err)
+			formJsonResponse(w, 500, res)
+			return
+		}
+		cfg.respondWithConversation(w, r, 200, existing)
+		return
+	}
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	for _, memberID := range append([]uuid.UUID{userUUID}, memberIDs...) {
+		err = qtx.AddConversationMember(r.Context(), database.AddConversationMemberParams{
+			ConversationID: conversation.ID,
+			UserID:         memberID,
+		})
+		if err != nil {
+			res := fmt.Sprintf(`{"error":"%v"}`, err)
+			formJsonResponse(w, 500, res)
+			return
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+	cfg.respondWithConversation(w, r, 201, conversation)
+}
+
+func (cfg *apiConfig) respondWithConversation(w http.ResponseWriter, r *http.Request, status int, c database.Conversation) {
+	members, err := cfg.conversationMembers(r.Context(), []uuid.UUID{c.ID})
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	conversation := Conversation{
+		ID:        c.ID,
+		CreatedAt: c.CreatedAt,
+		UpdatedAt: c.UpdatedAt,
+		Members:   members[c.ID],
+	}
+	jsr, err := json.Marshal(conversation)
+	if err != nil {
+		panic(err)
+	}
+	respondWithJson(w, status, jsr)
+}
+
+// GetConversations lists the user's conversations, most recently active
+// first.
+func (cfg *apiConfig) GetConversations(w http.ResponseWriter, r *http.Request) {
+	userUUID, err := cfg.authenticate(r)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 401, res)
+		return
+	}
+
+	before, limit, err := parsePage(r, newestFirst)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 400, res)
+		return
+	}
+
+	rows, err := cfg.dbQueries.GetConversations(r.Context(), database.GetConversationsParams{
+		UserID:          userUUID,
+		BeforeUpdatedAt: before.CreatedAt,
+		BeforeID:        before.ID,
+		PageSize:        limit,
+	})
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	ids := []uuid.UUID{}
+	for _, row := range rows {
+		ids = append(ids, row.ID)
+	}
+	members, err := cfg.conversationMembers(r.Context(), ids)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	conversations := []Conversation{}
+	for _, row := range rows {
+		conversations = append(conversations, Conversation{
+			ID:          row.ID,
+			CreatedAt:   row.CreatedAt,
+			UpdatedAt:   row.UpdatedAt,
+			Members:     members[row.ID],
+			UnreadCount: row.UnreadCount,
+		})
+	}
+
+	if len(rows) == int(limit) {
+		last := rows[len(rows)-1]
+		setNextPage(w, r, pageCursor{CreatedAt: last.UpdatedAt, ID: last.ID})
+	}
+	jsr, err := json.Marshal(conversations)
+	if err != nil {
+		panic(err)
+	}
+	respondWithJson(w, 200, jsr)
+}
+
+// GetMessages pages through a conversation newest first. Each message's
+// read_by lists the other members who have read up to it.
+func (cfg *apiConfig) GetMessages(w http.ResponseWriter, r *http.Request) {
+	userUUID, err := cfg.authenticate(r)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 401, res)
+		return
+	}
+
+	conversation, code, err := cfg.memberConversation(r, userUUID)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, code, res)
+		return
+	}
+
+	before, limit, err := parsePage(r, newestFirst)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 400, res)
+		return
+	}
+
+	rows, err := cfg.dbQueries.GetMessages(r.Context(), database.GetMessagesParams{
+		ConversationID:  conversation.ID,
+		BeforeCreatedAt: before.CreatedAt,
+		BeforeID:        before.ID,
+		PageSize:        limit,
+	})
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+	members, err := cfg.conversationMembers(r.Context(), []uuid.UUID{conversation.ID})
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	messages := []Message{}
+	for _, row := range rows {
+		messages = append(messages, mapMessage(row, members[conversation.ID]))
+	}
+
+	if len(rows) == int(limit) {
+		last := rows[len(rows)-1]
+		setNextPage(w, r, pageCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+	jsr, err := json.Marshal(messages)
+	if err != nil {
+		panic(err)
+	}
+	respondWithJson(w, 200, jsr)
+}
+
+// SendMessage posts to a conversation. Nobody can send to a conversation
+// that has someone in it with a block between them and the sender.
+func (cfg *apiConfig) SendMessage(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Body string `json:"body"`
+	}
+
+	userUUID, err := cfg.authenticate(r)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 401, res)
+		return
+	}
+
+	conversation, code, err := cfg.memberConversation(r, userUUID)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, code, res)
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 400, res)
+		return
+	}
+	if strings.TrimSpace(params.Body) == "" {
+		res := `{"error":"a message needs a body"}`
+		formJsonResponse(w, 400, res)
+		return
+	}
+	err = checkBody(params.Body, maxMessageLength)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 400, res)
+		return
+	}
+
+	blocked, err := cfg.dbQueries.ConversationHasBlock(r.Context(), database.ConversationHasBlockParams{
+		ConversationID: conversation.ID,
+		UserID:         userUUID,
+	})
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+	if blocked {
+		res := `{"error":"you can't send messages to this conversation"}`
+		formJsonResponse(w, 403, res)
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	dbMessage, err := qtx.CreateMessage(r.Context(), database.CreateMessageParams{
+		ConversationID: conversation.ID,
+		SenderID:       userUUID,
+		Body:           params.Body,
+	})
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+	err = qtx.TouchConversation(r.Context(), conversation.ID)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+	err = qtx.MarkConversationRead(r.Context(), database.MarkConversationReadParams{
+		ConversationID: conversation.ID,
+		UserID:         userUUID,
+	})
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	jsr, err := json.Marshal(mapMessage(dbMessage, nil))
+	if err != nil {
+		panic(err)
+	}
+	respondWithJson(w, 201, jsr)
+}
+
+// MarkConversationRead records that the user has read everything in the
+// conversation so far, which is what other members see as read receipts.
+func (cfg *apiConfig) MarkConversationRead(w http.ResponseWriter, r *http.Request) {
+	userUUID, err := cfg.authenticate(r)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 401, res)
+		return
+	}
+
+	conversation, code, err := cfg.memberConversation(r, userUUID)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, code, res)
+		return
+	}
+
+	err = cfg.dbQueries.MarkConversationRead(r.Context(), database.MarkConversationReadParams{
+		ConversationID: conversation.ID,
+		UserID:         userUUID,
+	})
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+	w.WriteHeader(204)
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"chirpy/internal/database"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// defaultRestoreWindow is how long a deleted chirp can be restored before
+// it's purged, unless CHIRP_RESTORE_WINDOW says otherwise.
+const defaultRestoreWindow = 30 * 24 * time.Hour
+
+const (
+	moderationDeleteChirp  = "delete_chirp"
+	moderationRestoreChirp = "restore_chirp"
+)
+
+// RestoreChirp brings back a deleted chirp within the restore window. An
+// author can undo their own deletion, but only a moderator can undo a
+// moderator's.
+func (cfg *apiConfig) RestoreChirp(w http.ResponseWriter, r *http.Request) {
+	userUUID, err := cfg.authenticate(r)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 401, res)
+		return
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpid"))
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 400, res)
+		return
+	}
+
+	deleted, err := cfg.dbQueries.GetDeletedChirp(r.Context(), chirpID)
+	if errors.Is(err, sql.ErrNoRows) {
+		formJsonResponse(w, 404, `{"error":"no deleted chirp with that id"}`)
+		return
+	}
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	moderator := false
+	if deleted.UserID != userUUID || deleted.DeletedBy.UUID != userUUID {
+		moderator, err = cfg.dbQueries.IsModerator(r.Context(), userUUID)
+		if err != nil {
+			res := fmt.Sprintf(`{"error":"%v"}`, err)
+			formJsonResponse(w, 500, res)
+			return
+		}
+	}
+	if !moderator && deleted.UserID != userUUID {
+		formJsonResponse(w, 404, `{"error":"no deleted chirp with that id"}`)
+		return
+	}
+	if !moderator && deleted.DeletedBy.UUID != userUUID {
+		formJsonResponse(w, 403, `{"error":"this chirp was removed by a moderator"}`)
+		return
+	}
+	if deleted.DeletedAt.Time.Before(time.Now().Add(-cfg.restoreWindow)) {
+		formJsonResponse(w, 410, `{"error":"this chirp can no longer be restored"}`)
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	dbChirp, err := qtx.RestoreChirp(r.Context(), chirpID)
+	if errors.Is(err, sql.ErrNoRows) {
+		formJsonResponse(w, 404, `{"error":"no deleted chirp with that id"}`)
+		return
+	}
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	if deleted.UserID != userUUID {
+		err = qtx.CreateModerationAction(r.Context(), database.CreateModerationActionParams{
+			ModeratorID: uuid.NullUUID{UUID: userUUID, Valid: true},
+			Action:      moderationRestoreChirp,
+			ChirpID:     chirpID,
+			AuthorID:    deleted.UserID,
+		})
+		if err != nil {
+			res := fmt.Sprintf(`{"error":"%v"}`, err)
+			formJsonResponse(w, 500, res)
+			return
+		}
+	}
+
+	// Streams treat a restored chirp as a new one.
+	event, err := qtx.CreateChirpEvent(r.Context(), database.CreateChirpEventParams{
+		Kind:    chirpEventCreated,
+		ChirpID: dbChirp.ID,
+		UserID:  dbChirp.UserID,
+	})
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+	cfg.events.ChirpEventPublished(event)
+	err = cfg.federateChirp(r.Context(), dbChirp, activityCreate)
+	if err != nil {
+		log.Printf("federation: %v", err)
+	}
+
+	chirps := []Chirp{mapChirp(dbChirp)}
+	err = cfg.attachEntities(r.Context(), chirps, userUUID)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	jsr, err := json.Marshal(chirps[0])
+	if err != nil {
+		panic(err)
+	}
+	respondWithJson(w, 200, jsr)
+}
+
+// runChirpPurger hard-deletes chirps whose restore window has passed,
+// taking their mentions, hashtags, polls and notifications with them.
+// Their media is left unattached for runMediaSweeper.
+func (cfg *apiConfig) runChirpPurger(ctx context.Context) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for {
+		before := time.Now().Add(-cfg.restoreWindow)
+		_, err := cfg.dbQueries.PurgeDeletedChirps(ctx, sql.NullTime{Time: before, Valid: true})
+		if err != nil {
+			log.Printf("purge: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
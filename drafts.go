@@ -0,0 +1,507 @@
+package main
+
+import (
+	"chirpy/internal/database"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// A scheduled chirp that fails to publish is tried again after
+// draftRetryBackoff, doubling each time, up to maxDraftAttempts.
+const (
+	draftRetryBackoff = time.Minute
+	maxDraftAttempts  = 5
+)
+
+// nullTime tells a field left out of a PATCH body apart from one set to
+// null.
+type nullTime struct {
+	Set  bool
+	Time *time.Time
+}
+
+func (t *nullTime) UnmarshalJSON(data []byte) error {
+	t.Set = true
+	return json.Unmarshal(data, &t.Time)
+}
+
+func mapDraft(d database.Draft) Draft {
+	draft := Draft{
+		ID:             d.ID,
+		CreatedAt:      d.CreatedAt,
+		UpdatedAt:      d.UpdatedAt,
+		Body:           d.Body,
+		Visibility:     d.Visibility,
+		ContentWarning: d.ContentWarning,
+		Error:          d.LastError,
+	}
+	if d.PublishAt.Valid {
+		draft.PublishAt = &d.PublishAt.Time
+	}
+	return draft
+}
+
+// checkPublishAt turns an optional publish_at into what's stored: nothing
+// for a plain draft, or a time in the future for a scheduled chirp. The
+// column has no time zone, so it holds UTC whatever offset the client sent.
+func checkPublishAt(publishAt *time.Time) (sql.NullTime, error) {
+	if publishAt == nil {
+		return sql.NullTime{}, nil
+	}
+	if !publishAt.After(time.Now()) {
+		return sql.NullTime{}, errors.New("publish_at must be in the future")
+	}
+	return sql.NullTime{Time: publishAt.UTC(), Valid: true}, nil
+}
+
+// CreateDraft saves a chirp to publish later: by hand if it has no
+// publish_at, or by the scheduler once publish_at comes round.
+func (cfg *apiConfig) CreateDraft(w http.ResponseWriter, r *http.Request) {
+	type cred struct {
+		Body           string     `json:"body"`
+		Visibility     string     `json:"visibility"`
+		ContentWarning string     `json:"content_warning"`
+		PublishAt      *time.Time `json:"publish_at"`
+	}
+
+	userUUID, err := cfg.authenticate(r)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 401, res)
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := cred{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 400, res)
+		return
+	}
+
+	visibility, contentWarning, err := validateChirpOptions(params.Body, params.Visibility, params.ContentWarning)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 400, res)
+		return
+	}
+	publishAt, err := checkPublishAt(params.PublishAt)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 400, res)
+		return
+	}
+
+	draft, err := cfg.dbQueries.CreateDraft(r.Context(), database.CreateDraftParams{
+		UserID:         userUUID,
+		Body:           params.Body,
+		Visibility:     visibility,
+		ContentWarning: contentWarning,
+		PublishAt:      publishAt,
+	})
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	jsr, err := json.Marshal(mapDraft(draft))
+	if err != nil {
+		panic(err)
+	}
+	respondWithJson(w, 201, jsr)
+}
+
+// GetDrafts lists the caller's drafts newest first. ?scheduled=true keeps
+// only scheduled chirps and ?scheduled=false only plain drafts.
+func (cfg *apiConfig) GetDrafts(w http.ResponseWriter, r *http.Request) {
+	userUUID, err := cfg.authenticate(r)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 401, res)
+		return
+	}
+
+	before, limit, err := parsePage(r, newestFirst)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 400, res)
+		return
+	}
+
+	params := database.GetDraftsParams{
+		UserID:          userUUID,
+		BeforeCreatedAt: before.CreatedAt,
+		BeforeID:        before.ID,
+		PageSize:        limit,
+	}
+	switch r.URL.Query().Get("scheduled") {
+	case "":
+	case "true":
+		params.Scheduled = sql.NullBool{Bool: true, Valid: true}
+	case "false":
+		params.Scheduled = sql.NullBool{Bool: false, Valid: true}
+	default:
+		formJsonResponse(w, 400, `{"error":"scheduled must be true or false"}`)
+		return
+	}
+
+	rows, err := cfg.dbQueries.GetDrafts(r.Context(), params)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	drafts := []Draft{}
+	for _, d := range rows {
+		drafts = append(drafts, mapDraft(d))
+	}
+	if len(rows) == int(limit) {
+		last := rows[len(rows)-1]
+		setNextPage(w, r, pageCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+
+	jsr, err := json.Marshal(drafts)
+	if err != nil {
+		panic(err)
+	}
+	respondWithJson(w, 200, jsr)
+}
+
+// UpdateDraft edits a draft or scheduled chirp. Setting publish_at
+// schedules it, or reschedules it; setting it to null turns it back into a
+// plain draft. A chirp the scheduler is publishing can no longer be
+// edited, and comes back as not found.
+func (cfg *apiConfig) UpdateDraft(w http.ResponseWriter, r *http.Request) {
+	type draftUpdate struct {
+		Body           *string  `json:"body"`
+		Visibility     *string  `json:"visibility"`
+		ContentWarning *string  `json:"content_warning"`
+		PublishAt      nullTime `json:"publish_at"`
+	}
+
+	userUUID, err := cfg.authenticate(r)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 401, res)
+		return
+	}
+
+	draftID, err := uuid.Parse(r.PathValue("draftid"))
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 400, res)
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	update := draftUpdate{}
+	err = decoder.Decode(&update)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 400, res)
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	current, err := qtx.LockDraft(r.Context(), database.LockDraftParams{
+		ID:     draftID,
+		UserID: userUUID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		formJsonResponse(w, 404, `{"error":"draft not found"}`)
+		return
+	}
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	params := database.UpdateDraftParams{
+		ID:             current.ID,
+		UserID:         current.UserID,
+		Body:           current.Body,
+		Visibility:     current.Visibility,
+		ContentWarning: current.ContentWarning,
+		PublishAt:      current.PublishAt,
+	}
+	if update.Body != nil {
+		params.Body = *update.Body
+	}
+	if update.Visibility != nil {
+		params.Visibility = *update.Visibility
+	}
+	if update.ContentWarning != nil {
+		params.ContentWarning = *update.ContentWarning
+	}
+	params.Visibility, params.ContentWarning, err = validateChirpOptions(params.Body, params.Visibility, params.ContentWarning)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 400, res)
+		return
+	}
+	if update.PublishAt.Set {
+		params.PublishAt, err = checkPublishAt(update.PublishAt.Time)
+		if err != nil {
+			res := fmt.Sprintf(`{"error":"%v"}`, err)
+			formJsonResponse(w, 400, res)
+			return
+		}
+	}
+
+	draft, err := qtx.UpdateDraft(r.Context(), params)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	jsr, err := json.Marshal(mapDraft(draft))
+	if err != nil {
+		panic(err)
+	}
+	respondWithJson(w, 200, jsr)
+}
+
+// DeleteDraft discards a draft, or cancels a scheduled chirp.
+func (cfg *apiConfig) DeleteDraft(w http.ResponseWriter, r *http.Request) {
+	userUUID, err := cfg.authenticate(r)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 401, res)
+		return
+	}
+
+	draftID, err := uuid.Parse(r.PathValue("draftid"))
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 400, res)
+		return
+	}
+
+	n, err := cfg.dbQueries.DeleteDraft(r.Context(), database.DeleteDraftParams{
+		ID:     draftID,
+		UserID: userUUID,
+	})
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+	if n == 0 {
+		formJsonResponse(w, 404, `{"error":"draft not found"}`)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+// PublishDraft publishes a draft or scheduled chirp right away.
+func (cfg *apiConfig) PublishDraft(w http.ResponseWriter, r *http.Request) {
+	userUUID, err := cfg.authenticate(r)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 401, res)
+		return
+	}
+
+	draftID, err := uuid.Parse(r.PathValue("draftid"))
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 400, res)
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	draft, err := qtx.LockDraft(r.Context(), database.LockDraftParams{
+		ID:     draftID,
+		UserID: userUUID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		formJsonResponse(w, 404, `{"error":"draft not found"}`)
+		return
+	}
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	published, err := publishDraft(r.Context(), qtx, draft)
+	if errors.Is(err, errNoMentions) {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 400, res)
+		return
+	}
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	err = cfg.announceChirp(r.Context(), published)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	chirps := []Chirp{mapChirp(published.chirp)}
+	err = cfg.attachEntities(r.Context(), chirps, userUUID)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	jsr, err := json.Marshal(chirps[0])
+	if err != nil {
+		panic(err)
+	}
+	respondWithJson(w, 201, jsr)
+}
+
+// publishDraft turns a locked draft into a chirp.
+func publishDraft(ctx context.Context, qtx *database.Queries, draft database.Draft) (publishedChirp, error) {
+	published, err := insertChirp(ctx, qtx, database.CreateChirpParams{
+		Body:           draft.Body,
+		UserID:         draft.UserID,
+		Visibility:     draft.Visibility,
+		ContentWarning: draft.ContentWarning,
+	})
+	if err != nil {
+		return published, err
+	}
+	_, err = qtx.DeleteDraft(ctx, database.DeleteDraftParams{
+		ID:     draft.ID,
+		UserID: draft.UserID,
+	})
+	return published, err
+}
+
+// runScheduler publishes scheduled chirps as they come due. Each one is
+// claimed with FOR UPDATE SKIP LOCKED, so any number of replicas can run
+// it side by side without publishing anything twice.
+func (cfg *apiConfig) runScheduler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		for {
+			published, err := cfg.publishDueDraft(ctx)
+			if err != nil {
+				log.Printf("scheduler: %v", err)
+			}
+			if !published {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// publishDueDraft publishes the next due scheduled chirp, reporting
+// whether there was one. One that can no longer be published, such as a
+// mentioned-only chirp whose mentions have all gone, goes back to the
+// drafts with the reason in last_error. One that fails for any other
+// reason is put off with retryDraft, so it can't hold up the rest.
+func (cfg *apiConfig) publishDueDraft(ctx context.Context) (bool, error) {
+	tx, err := cfg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	draft, err := qtx.ClaimDueDraft(ctx, sql.NullTime{Time: time.Now().UTC(), Valid: true})
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	published, err := publishDraft(ctx, qtx, draft)
+	if errors.Is(err, errNoMentions) {
+		tx.Rollback()
+		err = cfg.dbQueries.UnscheduleDraft(ctx, database.UnscheduleDraftParams{
+			ID:        draft.ID,
+			LastError: err.Error(),
+		})
+		return err == nil, err
+	}
+	if err != nil {
+		tx.Rollback()
+		return cfg.retryDraft(ctx, draft, err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return false, err
+	}
+	return true, cfg.announceChirp(ctx, published)
+}
+
+// retryDraft records why a scheduled chirp failed to publish and tries it
+// again after draftRetryBackoff, doubling each time. After
+// maxDraftAttempts it goes back to the drafts.
+func (cfg *apiConfig) retryDraft(ctx context.Context, draft database.Draft, cause error) (bool, error) {
+	log.Printf("scheduler: draft %s: %v", draft.ID, cause)
+	if draft.Attempts+1 >= maxDraftAttempts {
+		err := cfg.dbQueries.UnscheduleDraft(ctx, database.UnscheduleDraftParams{
+			ID:        draft.ID,
+			LastError: cause.Error(),
+		})
+		return err == nil, err
+	}
+	err := cfg.dbQueries.RetryDraft(ctx, database.RetryDraftParams{
+		ID:        draft.ID,
+		RetryAt:   sql.NullTime{Time: time.Now().UTC().Add(draftRetryBackoff << draft.Attempts), Valid: true},
+		LastError: cause.Error(),
+	})
+	return err == nil, err
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"chirpy/internal/database"
+	"chirpy/internal/entities"
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// recordMentions resolves the @handles in a chirp to users, stores them as
+// mention entities and notifies each mentioned user once. Handles that
+// don't belong to anyone, or belong to someone with a block between them and
+// the author, are left as plain text. It returns the notifications it
+// created, one for each user other than the author who was mentioned.
+func recordMentions(ctx context.Context, q *database.Queries, chirp database.Chirp) ([]database.Notification, error) {
+	notifications := []database.Notification{}
+	mentions := entities.ParseMentions(chirp.Body)
+	if len(mentions) == 0 {
+		return notifications, nil
+	}
+
+	handles := []string{}
+	for _, m := range mentions {
+		handles = append(handles, entities.NormalizeHandle(m.Handle))
+	}
+	users, err := q.GetUsersByHandles(ctx, database.GetUsersByHandlesParams{
+		Handles:  handles,
+		AuthorID: chirp.UserID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	userIDs := map[string]uuid.UUID{}
+	for _, u := range users {
+		userIDs[entities.NormalizeHandle(u.Handle)] = u.ID
+	}
+
+	notified := map[uuid.UUID]bool{}
+	for _, m := range mentions {
+		userID, ok := userIDs[entities.NormalizeHandle(m.Handle)]
+		if !ok {
+			continue
+		}
+
+		err = q.CreateChirpMention(ctx, database.CreateChirpMentionParams{
+			ChirpID:   chirp.ID,
+			UserID:    userID,
+			Handle:    m.Handle,
+			ByteStart: int32(m.Start),
+			ByteEnd:   int32(m.End),
+			CharStart: int32(m.CharStart),
+			CharEnd:   int32(m.CharEnd),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if userID == chirp.UserID || notified[userID] {
+			continue
+		}
+		notified[userID] = true
+		n, err := q.CreateNotification(ctx, database.CreateNotificationParams{
+			UserID:  userID,
+			ActorID: chirp.UserID,
+			Kind:    notificationMention,
+			ChirpID: uuid.NullUUID{UUID: chirp.ID, Valid: true},
+		})
+		if err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, n)
+	}
+	return notifications, nil
+}
+
+// recordHashtags stores the #tags in a chirp so it can be found by tag.
+func recordHashtags(ctx context.Context, q *database.Queries, chirp database.Chirp) error {
+	for _, h := range entities.ParseHashtags(chirp.Body) {
+		err := q.CreateChirpHashtag(ctx, database.CreateChirpHashtagParams{
+			ChirpID:   chirp.ID,
+			Tag:       h.Tag,
+			ByteStart: int32(h.Start),
+			ByteEnd:   int32(h.End),
+			CharStart: int32(h.CharStart),
+			CharEnd:   int32(h.CharEnd),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordLinks stores the links in a chirp and queues a preview for each
+// one that hasn't been seen before. Previews are fetched later by
+// runLinkPreviewer, so posting never waits on someone else's server.
+func recordLinks(ctx context.Context, q *database.Queries, chirp database.Chirp) error {
+	for _, l := range entities.ParseLinks(chirp.Body) {
+		err := q.CreateLinkPreview(ctx, l.URL)
+		if err != nil {
+			return err
+		}
+		err = q.CreateChirpLink(ctx, database.CreateChirpLinkParams{
+			ChirpID:   chirp.ID,
+			Url:       l.URL,
+			ByteStart: int32(l.Start),
+			ByteEnd:   int32(l.End),
+			CharStart: int32(l.CharStart),
+			CharEnd:   int32(l.CharEnd),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// attachEntities fills in the entities, media, pins and polls of already
+// mapped chirps with one query each for the whole batch. Polls depend on
+// who is looking, so it takes the viewer too.
+func (cfg *apiConfig) attachEntities(ctx context.Context, chirps []Chirp, viewer uuid.UUID) error {
+	if len(chirps) == 0 {
+		return nil
+	}
+
+	ids := []uuid.UUID{}
+	index := map[uuid.UUID]int{}
+	for i, c := range chirps {
+		ids = append(ids, c.ID)
+		index[c.ID] = i
+	}
+
+	mentions, err := cfg.dbQueries.GetMentionsForChirps(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for _, m := range mentions {
+		i := index[m.ChirpID]
+		chirps[i].Entities.Mentions = append(chirps[i].Entities.Mentions, MentionEntity{
+			UserID:    m.UserID,
+			Handle:    m.Handle,
+			ByteStart: m.ByteStart,
+			ByteEnd:   m.ByteEnd,
+			CharStart: m.CharStart,
+			CharEnd:   m.CharEnd,
+		})
+	}
+
+	hashtags, err := cfg.dbQueries.GetHashtagsForChirps(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for _, h := range hashtags {
+		i := index[h.ChirpID]
+		chirps[i].Entities.Hashtags = append(chirps[i].Entities.Hashtags, HashtagEntity{
+			Tag:       h.Tag,
+			ByteStart: h.ByteStart,
+			ByteEnd:   h.ByteEnd,
+			CharStart: h.CharStart,
+			CharEnd:   h.CharEnd,
+		})
+	}
+
+	links, err := cfg.dbQueries.GetLinksForChirps(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for _, l := range links {
+		i := index[l.ChirpID]
+		link := LinkEntity{
+			URL:       l.Url,
+			ByteStart: l.ByteStart,
+			ByteEnd:   l.ByteEnd,
+			CharStart: l.CharStart,
+			CharEnd:   l.CharEnd,
+		}
+		if l.FetchedAt.Valid && !l.Failed {
+			link.Preview = &LinkPreview{
+				Title:       l.Title,
+				Description: l.Description,
+				ImageURL:    l.ImageUrl,
+			}
+		}
+		chirps[i].Entities.Links = append(chirps[i].Entities.Links, link)
+	}
+
+	media, err := cfg.dbQueries.GetMediaForChirps(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for _, m := range media {
+		i := index[m.ChirpID.UUID]
+		chirps[i].Media = append(chirps[i].Media, mapMedia(m))
+	}
+
+	pinned, err := cfg.dbQueries.GetPinnedForChirps(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for _, id := range pinned {
+		chirps[index[id]].Pinned = true
+	}
+
+	return cfg.attachPolls(ctx, chirps, ids, index, viewer)
+}
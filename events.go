@@ -0,0 +1,316 @@
+package main
+
+import (
+	"chirpy/internal/database"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// Kinds of chirp_events row. Chirps can't be edited yet; when they can, the
+// edit path should record an "edited" event the same way.
+const (
+	chirpEventCreated = "created"
+	chirpEventDeleted = "deleted"
+)
+
+// Channels the database NOTIFYs on when a chirp event or notification is
+// inserted.
+const (
+	chirpEventsChannel    = "chirp_events"
+	notificationsChannel  = "notifications"
+	chirpEventRetention   = 24 * time.Hour
+	chirpEventsReplayPage = 100
+	chirpEventCacheSize   = 256
+)
+
+// chirpEventCache loads the chirp of each chirp event once for every
+// stream and WebSocket session, rather than each loading it for itself;
+// they only check for themselves whether the viewer gets the event. Only
+// the most recent events are kept, so one far enough behind to miss an
+// event loads its chirp again.
+type chirpEventCache struct {
+	cfg     *apiConfig
+	mu      sync.Mutex
+	entries map[int64]*cachedEventChirp
+	order   []int64
+}
+
+type cachedEventChirp struct {
+	once  sync.Once
+	chirp *Chirp
+	err   error
+}
+
+func newChirpEventCache(cfg *apiConfig) *chirpEventCache {
+	return &chirpEventCache{cfg: cfg, entries: map[int64]*cachedEventChirp{}}
+}
+
+// get returns event's chirp, or nil if it's gone since: purged, or for a
+// created event deleted, and for a deleted event restored.
+func (c *chirpEventCache) get(ctx context.Context, event database.ChirpEvent) (*Chirp, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[event.ID]
+	if !ok {
+		entry = &cachedEventChirp{}
+		c.entries[event.ID] = entry
+		c.order = append(c.order, event.ID)
+		if len(c.order) > chirpEventCacheSize {
+			delete(c.entries, c.order[0])
+			c.order = c.order[1:]
+		}
+	}
+	c.mu.Unlock()
+
+	entry.once.Do(func() {
+		// Others wait on this load, so it mustn't fail because the
+		// stream that started it went away.
+		entry.chirp, entry.err = c.load(context.WithoutCancel(ctx), event)
+	})
+	return entry.chirp, entry.err
+}
+
+// load reads a created chirp as its author sees it, the one viewer who can
+// see every chirp they haven't deleted. Entities are attached for nobody
+// in particular, so a poll comes out as it looks to someone who hasn't
+// voted; each viewer fills in their own votes with withViewerPoll. A
+// deleted chirp is only mapped, since its body is all anyone looks at.
+func (c *chirpEventCache) load(ctx context.Context, event database.ChirpEvent) (*Chirp, error) {
+	var dbChirp database.Chirp
+	var err error
+	switch event.Kind {
+	case chirpEventCreated:
+		dbChirp, err = c.cfg.dbQueries.GetChirp(ctx, database.GetChirpParams{
+			ID:       event.ChirpID,
+			ViewerID: event.UserID,
+		})
+	case chirpEventDeleted:
+		dbChirp, err = c.cfg.dbQueries.GetDeletedChirp(ctx, event.ChirpID)
+	default:
+		return nil, nil
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	chirps := []Chirp{mapChirp(dbChirp)}
+	if event.Kind == chirpEventCreated {
+		err = c.cfg.attachEntities(ctx, chirps, uuid.Nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &chirps[0], nil
+}
+
+// eventBus carries chirp events and notifications from the write paths to
+// the streams open on every replica. Write paths call it once the rows'
+// transaction has committed.
+type eventBus interface {
+	ChirpEventPublished(event database.ChirpEvent)
+	NotificationPublished(n database.Notification)
+}
+
+// localEvents hands events straight to this process's streams. It only
+// suits a single replica.
+type localEvents struct {
+	cfg *apiConfig
+}
+
+// ChirpEventPublished reloads the event, since its seq is only assigned
+// as its transaction commits.
+func (b localEvents) ChirpEventPublished(event database.ChirpEvent) {
+	event, err := b.cfg.dbQueries.GetChirpEvent(context.Background(), event.ID)
+	if err != nil {
+		log.Printf("events: %v", err)
+		return
+	}
+	b.cfg.chirpStream.Publish(event)
+}
+
+func (b localEvents) NotificationPublished(n database.Notification) {
+	b.cfg.notificationStream.Publish(n)
+}
+
+// postgresEvents leaves delivery to listenEvents: triggers on chirp_events
+// and notifications NOTIFY every replica, this one included, on commit.
+type postgresEvents struct{}
+
+func (b postgresEvents) ChirpEventPublished(event database.ChirpEvent) {}
+
+func (b postgresEvents) NotificationPublished(n database.Notification) {}
+
+// startEvents sets up the event bus for backend, "postgres" (the default)
+// or "local".
+func (cfg *apiConfig) startEvents(ctx context.Context, backend, dbURL string) error {
+	switch backend {
+	case "", "postgres":
+		cfg.events = postgresEvents{}
+		go cfg.listenEvents(ctx, dbURL)
+	case "local":
+		cfg.events = localEvents{cfg: cfg}
+	default:
+		return fmt.Errorf("STREAM_BACKEND must be postgres or local, got %q", backend)
+	}
+	go cfg.pruneChirpEvents(ctx)
+	return nil
+}
+
+// listenEvents keeps trying to LISTEN until it works, since streams are
+// dead without it. pq's listener reconnects by itself once it has.
+func (cfg *apiConfig) listenEvents(ctx context.Context, dbURL string) {
+	backoff := time.Second
+	for {
+		listener, err := openListener(dbURL)
+		if err == nil {
+			cfg.relayEvents(ctx, listener)
+			listener.Close()
+			return
+		}
+		log.Printf("events: %v; retrying in %v", err, backoff)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, time.Minute)
+	}
+}
+
+func openListener(dbURL string) (*pq.Listener, error) {
+	listener := pq.NewListener(dbURL, 10*time.Second, time.Minute, func(_ pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("events: %v", err)
+		}
+	})
+	for _, channel := range []string{chirpEventsChannel, notificationsChannel} {
+		err := listener.Listen(channel)
+		if err != nil {
+			listener.Close()
+			return nil, err
+		}
+	}
+	return listener, nil
+}
+
+// relayEvents publishes what listener hears to this process's streams.
+func (cfg *apiConfig) relayEvents(ctx context.Context, listener *pq.Listener) {
+	last, err := cfg.dbQueries.GetLatestChirpEventSeq(ctx)
+	if err != nil {
+		log.Printf("events: %v", err)
+	}
+
+	ping := time.NewTicker(90 * time.Second)
+	defer ping.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ping.C:
+			go listener.Ping()
+		case n := <-listener.Notify:
+			// A nil notification means the connection was re-established,
+			// and anything sent while it was down is lost. Chirp events are
+			// replayed from the table; missed notifications are still there
+			// to be listed, but aren't pushed.
+			if n == nil {
+				last = cfg.catchUpChirpEvents(ctx, last)
+				continue
+			}
+			switch n.Channel {
+			case chirpEventsChannel:
+				last = max(last, cfg.publishChirpEvent(ctx, n.Extra))
+			case notificationsChannel:
+				cfg.publishNotification(ctx, n.Extra)
+			}
+		}
+	}
+}
+
+// publishChirpEvent publishes the chirp event with the given id and
+// returns its seq, or 0 if it couldn't be loaded.
+func (cfg *apiConfig) publishChirpEvent(ctx context.Context, payload string) int64 {
+	id, err := strconv.ParseInt(payload, 10, 64)
+	if err != nil {
+		log.Printf("events: bad chirp event id %q", payload)
+		return 0
+	}
+	event, err := cfg.dbQueries.GetChirpEvent(ctx, id)
+	if err != nil {
+		log.Printf("events: %v", err)
+		return 0
+	}
+	cfg.chirpStream.Publish(event)
+	return event.Seq.Int64
+}
+
+func (cfg *apiConfig) publishNotification(ctx context.Context, payload string) {
+	id, err := uuid.Parse(payload)
+	if err != nil {
+		log.Printf("events: bad notification id %q", payload)
+		return
+	}
+	n, err := cfg.dbQueries.GetNotification(ctx, id)
+	if err != nil {
+		log.Printf("events: %v", err)
+		return
+	}
+	cfg.notificationStream.Publish(n)
+}
+
+// catchUpChirpEvents publishes the events committed after seq last and
+// returns the seq of the newest one.
+func (cfg *apiConfig) catchUpChirpEvents(ctx context.Context, last int64) int64 {
+	if last == 0 {
+		latest, err := cfg.dbQueries.GetLatestChirpEventSeq(ctx)
+		if err != nil {
+			log.Printf("events: %v", err)
+		}
+		return latest
+	}
+	for {
+		events, err := cfg.dbQueries.GetChirpEventsAfter(ctx, database.GetChirpEventsAfterParams{
+			AfterSeq: last,
+			PageSize: chirpEventsReplayPage,
+		})
+		if err != nil {
+			log.Printf("events: %v", err)
+			return last
+		}
+		for _, event := range events {
+			cfg.chirpStream.Publish(event)
+			last = event.Seq.Int64
+		}
+		if len(events) < chirpEventsReplayPage {
+			return last
+		}
+	}
+}
+
+// pruneChirpEvents drops events too old to resume from once an hour.
+func (cfg *apiConfig) pruneChirpEvents(ctx context.Context) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := cfg.dbQueries.DeleteChirpEventsBefore(ctx, time.Now().Add(-chirpEventRetention))
+			if err != nil {
+				log.Printf("events: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,805 @@
+package main
+
+import (
+	"chirpy/internal/activitypub"
+	"chirpy/internal/database"
+	"chirpy/internal/entities"
+	"context"
+	"crypto/rsa"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	activityCreate = "Create"
+	activityDelete = "Delete"
+
+	federationTimeout = 10 * time.Second
+	outboxPageSize    = 20
+
+	// A claimed delivery is left alone for deliveryLease, so a worker that
+	// dies mid-request doesn't lose it. Failed deliveries are retried after
+	// deliveryBackoff, doubling each time, until maxDeliveryAttempts.
+	deliveryLease       = time.Minute
+	deliveryBackoff     = time.Minute
+	maxDeliveryAttempts = 8
+
+	// minActorRefetch keeps a stream of badly signed requests from making
+	// us fetch the same actor over and over.
+	minActorRefetch = time.Minute
+)
+
+// federating reports whether Chirpy talks to other servers. Actor and
+// object ids have to be stable, so federation needs PUBLIC_URL.
+func (cfg *apiConfig) federating() bool {
+	return cfg.publicURL != ""
+}
+
+func (cfg *apiConfig) actorURL(userID uuid.UUID) string {
+	return cfg.publicURL + "/ap/users/" + userID.String()
+}
+
+func (cfg *apiConfig) keyID(userID uuid.UUID) string {
+	return cfg.actorURL(userID) + "#main-key"
+}
+
+func (cfg *apiConfig) noteURL(chirpID uuid.UUID) string {
+	return cfg.publicURL + "/ap/chirps/" + chirpID.String()
+}
+
+// activityID names an activity we send. Nothing is served there; other
+// servers only use it to tell activities apart.
+func (cfg *apiConfig) activityID(userID uuid.UUID) string {
+	return cfg.actorURL(userID) + "#activities/" + uuid.NewString()
+}
+
+func respondWithActivity(w http.ResponseWriter, status int, v any) {
+	jsr, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	w.Header().Set("Content-Type", activitypub.ContentType)
+	w.WriteHeader(status)
+	w.Write(jsr)
+}
+
+// actorKey returns the key a user's activities are signed with, making
+// one the first time it's needed.
+func (cfg *apiConfig) actorKey(ctx context.Context, userID uuid.UUID) (database.ActorKey, error) {
+	key, err := cfg.dbQueries.GetActorKey(ctx, userID)
+	if !errors.Is(err, sql.ErrNoRows) {
+		return key, err
+	}
+
+	private, err := activitypub.GenerateKey()
+	if err != nil {
+		return key, err
+	}
+	public, err := activitypub.EncodePublicKey(&private.PublicKey)
+	if err != nil {
+		return key, err
+	}
+	// Two requests may race to make the key; whichever is stored first wins.
+	err = cfg.dbQueries.CreateActorKey(ctx, database.CreateActorKeyParams{
+		UserID:        userID,
+		PublicKeyPem:  public,
+		PrivateKeyPem: activitypub.EncodePrivateKey(private),
+	})
+	if err != nil {
+		return key, err
+	}
+	return cfg.dbQueries.GetActorKey(ctx, userID)
+}
+
+// federatedUser loads the account named by the userid path value. Private
+// accounts aren't federated, so they don't exist as far as other servers
+// can tell.
+func (cfg *apiConfig) federatedUser(r *http.Request) (database.User, int, error) {
+	userID, err := uuid.Parse(r.PathValue("userid"))
+	if err != nil {
+		return database.User{}, 404, err
+	}
+	user, err := cfg.dbQueries.GetUserByID(r.Context(), userID)
+	if err != nil {
+		return user, 404, err
+	}
+	if user.Private {
+		return user, 404, errors.New("this account is private")
+	}
+	return user, 0, nil
+}
+
+// WebFinger maps acct:handle@host, where host is PUBLIC_URL's, to the
+// account's actor.
+func (cfg *apiConfig) WebFinger(w http.ResponseWriter, r *http.Request) {
+	resource := r.URL.Query().Get("resource")
+	handle, domain, ok := activitypub.ParseAccount(resource)
+	public, err := url.Parse(cfg.publicURL)
+	if !ok || err != nil || !strings.EqualFold(domain, public.Host) {
+		formJsonResponse(w, 404, `{"error":"unknown resource"}`)
+		return
+	}
+
+	user, err := cfg.dbQueries.GetUserByHandle(r.Context(), handle)
+	if err != nil || user.Private {
+		formJsonResponse(w, 404, `{"error":"unknown resource"}`)
+		return
+	}
+
+	jrd := activitypub.WebFinger{
+		Subject: "acct:" + user.Handle + "@" + public.Host,
+		Aliases: []string{cfg.actorURL(user.ID)},
+		Links: []activitypub.WebFingerLink{
+			{Rel: "self", Type: activitypub.ContentType, Href: cfg.actorURL(user.ID)},
+		},
+	}
+	jsr, err := json.Marshal(jrd)
+	if err != nil {
+		panic(err)
+	}
+	w.Header().Set("Content-Type", "application/jrd+json")
+	w.WriteHeader(200)
+	w.Write(jsr)
+}
+
+func (cfg *apiConfig) GetActor(w http.ResponseWriter, r *http.Request) {
+	user, status, err := cfg.federatedUser(r)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, status, res)
+		return
+	}
+
+	key, err := cfg.actorKey(r.Context(), user.ID)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	id := cfg.actorURL(user.ID)
+	actor := activitypub.Actor{
+		Context:           activitypub.Context,
+		ID:                id,
+		Type:              "Person",
+		PreferredUsername: user.Handle,
+		Name:              user.DisplayName,
+		Summary:           html.EscapeString(user.Bio),
+		URL:               cfg.publicURL + "/api/users/" + url.PathEscape(user.Handle),
+		Inbox:             id + "/inbox",
+		Outbox:            id + "/outbox",
+		Followers:         id + "/followers",
+		Endpoints:         &activitypub.Endpoints{SharedInbox: cfg.publicURL + "/ap/inbox"},
+		PublicKey: activitypub.PublicKey{
+			ID:           cfg.keyID(user.ID),
+			Owner:        id,
+			PublicKeyPem: key.PublicKeyPem,
+		},
+	}
+	if user.AvatarUrl != "" {
+		actor.Icon = &activitypub.Image{Type: "Image", URL: user.AvatarUrl}
+	}
+	respondWithActivity(w, 200, actor)
+}
+
+// GetOutbox lists an account's latest public chirps as Create activities.
+func (cfg *apiConfig) GetOutbox(w http.ResponseWriter, r *http.Request) {
+	user, status, err := cfg.federatedUser(r)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, status, res)
+		return
+	}
+
+	chirps, err := cfg.userChirps(r.Context(), user.ID, uuid.Nil, newestFirst, outboxPageSize)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	outbox := activitypub.OrderedCollection{
+		Context:      activitypub.Context,
+		ID:           cfg.actorURL(user.ID) + "/outbox",
+		Type:         "OrderedCollection",
+		OrderedItems: []any{},
+	}
+	for _, c := range chirps {
+		if c.Visibility != visibilityPublic {
+			continue
+		}
+		note := cfg.note(c)
+		create, err := activitypub.NewActivity(note.ID+"/activity", activityCreate, note.AttributedTo, note)
+		if err != nil {
+			panic(err)
+		}
+		create.Context = nil
+		create.Published = note.Published
+		create.To, create.Cc = note.To, note.Cc
+		outbox.OrderedItems = append(outbox.OrderedItems, create)
+	}
+	outbox.TotalItems = len(outbox.OrderedItems)
+	respondWithActivity(w, 200, outbox)
+}
+
+// GetActorFollowers gives the number of remote followers. Who they are is
+// left out, as other servers do by default.
+func (cfg *apiConfig) GetActorFollowers(w http.ResponseWriter, r *http.Request) {
+	user, status, err := cfg.federatedUser(r)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, status, res)
+		return
+	}
+
+	count, err := cfg.dbQueries.CountRemoteFollowers(r.Context(), user.ID)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	respondWithActivity(w, 200, activitypub.OrderedCollection{
+		Context:    activitypub.Context,
+		ID:         cfg.actorURL(user.ID) + "/followers",
+		Type:       "OrderedCollection",
+		TotalItems: int(count),
+	})
+}
+
+// GetNote serves a public chirp as a Note.
+func (cfg *apiConfig) GetNote(w http.ResponseWriter, r *http.Request) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpid"))
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 404, res)
+		return
+	}
+
+	dbChirp, err := cfg.dbQueries.GetChirp(r.Context(), database.GetChirpParams{
+		ID:       chirpID,
+		ViewerID: uuid.Nil,
+	})
+	if err != nil || dbChirp.Visibility != visibilityPublic {
+		formJsonResponse(w, 404, `{"error":"chirp not found"}`)
+		return
+	}
+
+	note := cfg.note(mapChirp(dbChirp))
+	note.Context = activitypub.Context
+	respondWithActivity(w, 200, note)
+}
+
+// note renders a chirp for other servers. Content is the escaped body;
+// hashtags are listed as tags so they can be followed there.
+func (cfg *apiConfig) note(c Chirp) activitypub.Note {
+	actor := cfg.actorURL(c.User_id)
+	note := activitypub.Note{
+		ID:           cfg.noteURL(c.ID),
+		Type:         "Note",
+		AttributedTo: actor,
+		Content:      "<p>" + strings.ReplaceAll(html.EscapeString(c.Body), "\n", "<br>") + "</p>",
+		Summary:      html.EscapeString(c.ContentWarning),
+		Sensitive:    c.ContentWarning != "",
+		Published:    c.CreatedAt.UTC().Format(time.RFC3339),
+		URL:          cfg.publicURL + "/api/chirps/" + c.ID.String(),
+		To:           []string{activitypub.Public},
+		Cc:           []string{actor + "/followers"},
+	}
+	for _, h := range entities.ParseHashtags(c.Body) {
+		note.Tag = append(note.Tag, activitypub.Tag{
+			Type: "Hashtag",
+			Href: cfg.publicURL + "/api/hashtags/" + url.PathEscape(h.Tag) + "/chirps",
+			Name: "#" + h.Tag,
+		})
+	}
+	return note
+}
+
+// federateChirp queues a Create or Delete of a chirp for every server
+// where the author has followers. Only public chirps by public accounts
+// leave Chirpy.
+func (cfg *apiConfig) federateChirp(ctx context.Context, c database.Chirp, typ string) error {
+	if !cfg.federating() || c.Visibility != visibilityPublic {
+		return nil
+	}
+	inboxes, err := cfg.dbQueries.GetFollowerInboxes(ctx, c.UserID)
+	if err != nil || len(inboxes) == 0 {
+		return err
+	}
+	author, err := cfg.dbQueries.GetUserByID(ctx, c.UserID)
+	if err != nil {
+		return err
+	}
+	if author.Private && typ == activityCreate {
+		return nil
+	}
+
+	note := cfg.note(mapChirp(c))
+	var object any = note
+	if typ == activityDelete {
+		object = map[string]string{"id": note.ID, "type": "Tombstone"}
+	}
+	activity, err := activitypub.NewActivity(cfg.activityID(c.UserID), typ, note.AttributedTo, object)
+	if err != nil {
+		return err
+	}
+	activity.To, activity.Cc = note.To, note.Cc
+	body, err := json.Marshal(activity)
+	if err != nil {
+		return err
+	}
+
+	for _, inbox := range inboxes {
+		err = cfg.dbQueries.EnqueueDelivery(ctx, database.EnqueueDeliveryParams{
+			UserID:   c.UserID,
+			Inbox:    inbox,
+			Activity: body,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sendActivity queues an activity from a user to a single inbox.
+func sendActivity(ctx context.Context, q *database.Queries, userID uuid.UUID, inbox string, activity activitypub.Activity) error {
+	body, err := json.Marshal(activity)
+	if err != nil {
+		return err
+	}
+	return q.EnqueueDelivery(ctx, database.EnqueueDeliveryParams{
+		UserID:   userID,
+		Inbox:    inbox,
+		Activity: body,
+	})
+}
+
+// remoteActor fetches the actor at uri and caches it. Handles are stored
+// as user@host, which is how people type them.
+func (cfg *apiConfig) remoteActor(ctx context.Context, uri string) (database.RemoteActor, error) {
+	actor, err := cfg.federation.FetchActor(ctx, uri)
+	if err != nil {
+		return database.RemoteActor{}, err
+	}
+	u, err := url.Parse(actor.ID)
+	if err != nil {
+		return database.RemoteActor{}, err
+	}
+	params := database.UpsertRemoteActorParams{
+		Uri:          actor.ID,
+		Handle:       actor.PreferredUsername + "@" + u.Host,
+		Inbox:        actor.Inbox,
+		PublicKeyID:  actor.PublicKey.ID,
+		PublicKeyPem: actor.PublicKey.PublicKeyPem,
+	}
+	if actor.Endpoints != nil {
+		params.SharedInbox = actor.Endpoints.SharedInbox
+	}
+	return cfg.dbQueries.UpsertRemoteActor(ctx, params)
+}
+
+// signerKey finds the actor behind a signature's key id, fetching it when
+// it isn't cached or refresh is set.
+func (cfg *apiConfig) signerKey(ctx context.Context, keyID string, refresh bool) (database.RemoteActor, error) {
+	if !refresh {
+		signer, err := cfg.dbQueries.GetRemoteActorByKeyID(ctx, keyID)
+		if !errors.Is(err, sql.ErrNoRows) {
+			return signer, err
+		}
+	}
+	signer, err := cfg.remoteActor(ctx, keyID)
+	if err != nil {
+		return signer, err
+	}
+	if signer.PublicKeyID != keyID {
+		return signer, fmt.Errorf("%w: %s doesn't publish key %s", activitypub.ErrBadSignature, signer.Uri, keyID)
+	}
+	return signer, nil
+}
+
+// verifySigner checks an inbox request's signature and returns who signed
+// it. A cached key that no longer verifies may have been rotated, so the
+// actor is fetched again once before giving up.
+func (cfg *apiConfig) verifySigner(r *http.Request, body []byte) (database.RemoteActor, error) {
+	var signer database.RemoteActor
+	lookup := func(refresh bool) func(string) (*rsa.PublicKey, error) {
+		return func(keyID string) (*rsa.PublicKey, error) {
+			var err error
+			signer, err = cfg.signerKey(r.Context(), keyID, refresh)
+			if err != nil {
+				return nil, err
+			}
+			return activitypub.ParsePublicKey(signer.PublicKeyPem)
+		}
+	}
+
+	_, err := activitypub.Verify(r, body, lookup(false))
+	if errors.Is(err, activitypub.ErrBadSignature) && signer.ID != uuid.Nil && time.Since(signer.FetchedAt) > minActorRefetch {
+		_, err = activitypub.Verify(r, body, lookup(true))
+	}
+	return signer, err
+}
+
+// localUserID is the user an actor URL of ours belongs to.
+func (cfg *apiConfig) localUserID(actor string) (uuid.UUID, bool) {
+	id, ok := strings.CutPrefix(actor, cfg.publicURL+"/ap/users/")
+	if !ok {
+		return uuid.Nil, false
+	}
+	userID, err := uuid.Parse(id)
+	return userID, err == nil
+}
+
+// Inbox takes activities from other servers, at an account's inbox or the
+// shared one. Follows of our accounts and answers to our own follows are
+// acted on; anything else is accepted and dropped, since remote chirps
+// aren't shown on Chirpy.
+func (cfg *apiConfig) Inbox(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, activitypub.MaxDocumentBytes))
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 400, res)
+		return
+	}
+
+	signer, err := cfg.verifySigner(r, body)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 401, res)
+		return
+	}
+
+	var activity activitypub.Activity
+	err = json.Unmarshal(body, &activity)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 400, res)
+		return
+	}
+	if activity.Actor != signer.Uri {
+		formJsonResponse(w, 401, `{"error":"activity is not signed by its actor"}`)
+		return
+	}
+
+	switch activity.Type {
+	case "Follow":
+		err = cfg.acceptFollow(r.Context(), signer, activity)
+	case "Undo":
+		_, err = cfg.dbQueries.DeleteRemoteFollower(r.Context(), database.DeleteRemoteFollowerParams{
+			ActorID:   signer.ID,
+			FollowUri: activity.ObjectID(),
+		})
+	case "Accept":
+		_, err = cfg.dbQueries.AcceptRemoteFollow(r.Context(), database.AcceptRemoteFollowParams{
+			ActorID:   signer.ID,
+			FollowUri: activity.ObjectID(),
+		})
+	case "Reject":
+		_, err = cfg.dbQueries.RejectRemoteFollow(r.Context(), database.RejectRemoteFollowParams{
+			ActorID:   signer.ID,
+			FollowUri: activity.ObjectID(),
+		})
+	case "Delete":
+		if activity.ObjectID() == signer.Uri {
+			_, err = cfg.dbQueries.DeleteRemoteActor(r.Context(), signer.Uri)
+		}
+	}
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+	w.WriteHeader(202)
+}
+
+// acceptFollow records a remote follower of a public account and sends
+// back an Accept. Follows of anyone else are ignored.
+func (cfg *apiConfig) acceptFollow(ctx context.Context, signer database.RemoteActor, follow activitypub.Activity) error {
+	userID, ok := cfg.localUserID(follow.ObjectID())
+	if !ok {
+		return nil
+	}
+	user, err := cfg.dbQueries.GetUserByID(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) || user.Private {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	err = cfg.dbQueries.CreateRemoteFollower(ctx, database.CreateRemoteFollowerParams{
+		UserID:    user.ID,
+		ActorID:   signer.ID,
+		FollowUri: follow.ID,
+	})
+	if err != nil {
+		return err
+	}
+
+	follow.Context = nil
+	accept, err := activitypub.NewActivity(cfg.activityID(user.ID), "Accept", cfg.actorURL(user.ID), follow)
+	if err != nil {
+		return err
+	}
+	return sendActivity(ctx, cfg.dbQueries, user.ID, signer.Inbox, accept)
+}
+
+// FollowRemote follows an account on another server, given as
+// user@example.com. The follow shows as pending until that server
+// accepts it.
+func (cfg *apiConfig) FollowRemote(w http.ResponseWriter, r *http.Request) {
+	userUUID, err := cfg.authenticate(r)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 401, res)
+		return
+	}
+
+	type parameters struct {
+		Account string `json:"account"`
+	}
+	params := parameters{}
+	err = json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 400, res)
+		return
+	}
+
+	user, err := cfg.dbQueries.GetUserByID(r.Context(), userUUID)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+	if user.Private {
+		formJsonResponse(w, 403, `{"error":"private accounts can't follow accounts on other servers"}`)
+		return
+	}
+
+	uri, err := cfg.federation.Finger(r.Context(), params.Account)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 404, res)
+		return
+	}
+	target, err := cfg.remoteActor(r.Context(), uri)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 502, res)
+		return
+	}
+
+	follow, err := activitypub.NewActivity(cfg.activityID(user.ID), "Follow", cfg.actorURL(user.ID), target.Uri)
+	if err != nil {
+		panic(err)
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	err = qtx.CreateRemoteFollow(r.Context(), database.CreateRemoteFollowParams{
+		UserID:    user.ID,
+		ActorID:   target.ID,
+		FollowUri: follow.ID,
+	})
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+	err = sendActivity(r.Context(), qtx, user.ID, target.Inbox, follow)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	jsr, err := json.Marshal(RemoteFollow{
+		Account:   target.Handle,
+		URI:       target.Uri,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		panic(err)
+	}
+	respondWithJson(w, 202, jsr)
+}
+
+func (cfg *apiConfig) GetRemoteFollows(w http.ResponseWriter, r *http.Request) {
+	userUUID, err := cfg.authenticate(r)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 401, res)
+		return
+	}
+
+	rows, err := cfg.dbQueries.GetRemoteFollows(r.Context(), userUUID)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+	follows := []RemoteFollow{}
+	for _, row := range rows {
+		follows = append(follows, RemoteFollow{
+			Account:   row.Handle,
+			URI:       row.Uri,
+			Accepted:  row.Accepted,
+			CreatedAt: row.CreatedAt,
+		})
+	}
+
+	jsr, err := json.Marshal(follows)
+	if err != nil {
+		panic(err)
+	}
+	respondWithJson(w, 200, jsr)
+}
+
+// UnfollowRemote stops following a remote account and tells its server
+// with an Undo.
+func (cfg *apiConfig) UnfollowRemote(w http.ResponseWriter, r *http.Request) {
+	userUUID, err := cfg.authenticate(r)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 401, res)
+		return
+	}
+
+	user, domain, ok := activitypub.ParseAccount(r.PathValue("account"))
+	if !ok {
+		formJsonResponse(w, 400, `{"error":"account must look like user@example.com"}`)
+		return
+	}
+	target, err := cfg.dbQueries.GetRemoteActorByHandle(r.Context(), user+"@"+domain)
+	if errors.Is(err, sql.ErrNoRows) {
+		formJsonResponse(w, 404, `{"error":"you don't follow this account"}`)
+		return
+	}
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	followURI, err := qtx.DeleteRemoteFollow(r.Context(), database.DeleteRemoteFollowParams{
+		UserID:  userUUID,
+		ActorID: target.ID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		formJsonResponse(w, 404, `{"error":"you don't follow this account"}`)
+		return
+	}
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	actor := cfg.actorURL(userUUID)
+	follow, err := activitypub.NewActivity(followURI, "Follow", actor, target.Uri)
+	if err != nil {
+		panic(err)
+	}
+	follow.Context = nil
+	undo, err := activitypub.NewActivity(cfg.activityID(userUUID), "Undo", actor, follow)
+	if err != nil {
+		panic(err)
+	}
+	err = sendActivity(r.Context(), qtx, userUUID, target.Inbox, undo)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+// runDeliveryWorker sends queued activities, retrying ones that fail.
+func (cfg *apiConfig) runDeliveryWorker(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		for {
+			ok, err := cfg.deliverNext(ctx)
+			if err != nil {
+				log.Printf("federation: %v", err)
+				break
+			}
+			if !ok {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// deliverNext sends the next due activity, reporting whether there was
+// one. A delivery that can't succeed, because the inbox is gone or it
+// has failed too often, is dropped.
+func (cfg *apiConfig) deliverNext(ctx context.Context) (bool, error) {
+	now := time.Now()
+	d, err := cfg.dbQueries.ClaimDelivery(ctx, database.ClaimDeliveryParams{
+		LeaseUntil: now.Add(deliveryLease),
+		Now:        now,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	key, err := cfg.actorKey(ctx, d.UserID)
+	if err != nil {
+		return false, err
+	}
+	private, err := activitypub.ParsePrivateKey(key.PrivateKeyPem)
+	if err != nil {
+		return false, err
+	}
+
+	err = cfg.federation.Deliver(ctx, d.Inbox, d.Activity, cfg.keyID(d.UserID), private)
+	if err == nil {
+		return true, cfg.dbQueries.DeleteDelivery(ctx, d.ID)
+	}
+
+	var status *activitypub.StatusError
+	if (errors.As(err, &status) && status.Permanent()) || d.Attempts >= maxDeliveryAttempts {
+		log.Printf("federation: giving up on %s after %d attempts: %v", d.Inbox, d.Attempts, err)
+		return true, cfg.dbQueries.DeleteDelivery(ctx, d.ID)
+	}
+	return true, cfg.dbQueries.RetryDelivery(ctx, database.RetryDeliveryParams{
+		ID:            d.ID,
+		NextAttemptAt: time.Now().Add(deliveryBackoff << (d.Attempts - 1)),
+		LastError:     err.Error(),
+	})
+}
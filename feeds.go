@@ -0,0 +1,268 @@
+package main
+
+import (
+	"chirpy/internal/database"
+	"chirpy/internal/entities"
+	"chirpy/internal/feed"
+	"chirpy/internal/httpcache"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+const (
+	feedAtom = "atom"
+	feedRSS  = "rss"
+
+	// feedMaxAge is how long feed readers and caches may reuse a feed
+	// before asking again. Most readers poll far more often than people
+	// chirp.
+	feedMaxAge       = 5 * time.Minute
+	maxFeedTitleRune = 80
+)
+
+// baseURL is the scheme and host links to Chirpy are built from: PUBLIC_URL
+// when it is set, otherwise whatever the request was sent to.
+func (cfg *apiConfig) baseURL(r *http.Request) string {
+	if cfg.publicURL != "" {
+		return cfg.publicURL
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+// UserFeed serves an account's public chirps as an Atom or RSS feed. Feeds
+// are read anonymously, so private accounts don't have one.
+func (cfg *apiConfig) UserFeed(format string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		target, err := cfg.dbQueries.GetUserByHandle(r.Context(), r.PathValue("handle"))
+		if err != nil {
+			res := fmt.Sprintf(`{"error":"%v"}`, err)
+			formJsonResponse(w, 404, res)
+			return
+		}
+		visible, err := cfg.dbQueries.CanViewAuthor(r.Context(), database.CanViewAuthorParams{
+			AuthorID: target.ID,
+			ViewerID: uuid.Nil,
+		})
+		if err != nil {
+			res := fmt.Sprintf(`{"error":"%v"}`, err)
+			formJsonResponse(w, 500, res)
+			return
+		}
+		if !visible {
+			formJsonResponse(w, 404, `{"error":"this account has no public feed"}`)
+			return
+		}
+
+		base := cfg.baseURL(r)
+		versions, err := cfg.dbQueries.GetUserFeedVersions(r.Context(), database.GetUserFeedVersionsParams{
+			AuthorID: target.ID,
+			ViewerID: uuid.Nil,
+			PageSize: defaultPageSize,
+		})
+		if err != nil {
+			res := fmt.Sprintf(`{"error":"%v"}`, err)
+			formJsonResponse(w, 500, res)
+			return
+		}
+		key := []string{format, base, target.Handle, target.DisplayName}
+		for _, v := range versions {
+			key = append(key, feedVersion(v.ID, v.UpdatedAt, target.Handle))
+		}
+		etag := feedTag(key)
+		if cfg.feedNotModified(w, r, format, etag) {
+			return
+		}
+
+		chirps, err := cfg.userChirps(r.Context(), target.ID, uuid.Nil, newestFirst, defaultPageSize)
+		if err != nil {
+			res := fmt.Sprintf(`{"error":"%v"}`, err)
+			formJsonResponse(w, 500, res)
+			return
+		}
+
+		f := feed.Feed{
+			Title:   "@" + target.Handle + " on Chirpy",
+			Link:    base + "/api/users/" + url.PathEscape(target.Handle),
+			Self:    base + r.URL.Path,
+			Updated: target.CreatedAt,
+		}
+		if target.DisplayName != "" {
+			f.Title = target.DisplayName + " (@" + target.Handle + ") on Chirpy"
+		}
+		cfg.writeFeed(w, r, format, f, chirps, etag)
+	}
+}
+
+// HashtagFeed serves the public chirps tagged with the tag in the path as
+// an Atom or RSS feed.
+func (cfg *apiConfig) HashtagFeed(format string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tag := entities.NormalizeHashtag(r.PathValue("tag"))
+		base := cfg.baseURL(r)
+		versions, err := cfg.dbQueries.GetHashtagFeedVersions(r.Context(), database.GetHashtagFeedVersionsParams{
+			Tag:      tag,
+			ViewerID: uuid.Nil,
+			PageSize: defaultPageSize,
+		})
+		if err != nil {
+			res := fmt.Sprintf(`{"error":"%v"}`, err)
+			formJsonResponse(w, 500, res)
+			return
+		}
+		key := []string{format, base, tag}
+		for _, v := range versions {
+			key = append(key, feedVersion(v.ID, v.UpdatedAt, v.Handle))
+		}
+		etag := feedTag(key)
+		if cfg.feedNotModified(w, r, format, etag) {
+			return
+		}
+
+		chirps, err := cfg.hashtagChirps(r.Context(), tag, uuid.Nil, newestFirst, defaultPageSize)
+		if err != nil {
+			res := fmt.Sprintf(`{"error":"%v"}`, err)
+			formJsonResponse(w, 500, res)
+			return
+		}
+
+		f := feed.Feed{
+			Title: "#" + tag + " on Chirpy",
+			Link:  base + "/api/hashtags/" + url.PathEscape(tag) + "/chirps",
+			Self:  base + r.URL.Path,
+		}
+		cfg.writeFeed(w, r, format, f, chirps, etag)
+	}
+}
+
+// feedVersion is what a feed entry's content depends on: the chirp, when
+// it last changed and its author's handle.
+func feedVersion(id uuid.UUID, updatedAt time.Time, handle string) string {
+	return id.String() + " " + updatedAt.UTC().Format(time.RFC3339Nano) + " @" + handle
+}
+
+// feedTag is the entity tag of a feed built from key, which lists
+// everything the feed's bytes depend on. Working it out takes one cheap
+// query, so a reader polling an unchanged feed gets its 304 before the
+// chirps and their entities are loaded.
+func feedTag(key []string) string {
+	return httpcache.ETag([]byte(strings.Join(key, "\n")))
+}
+
+func feedContentType(format string) string {
+	if format == feedRSS {
+		return "application/rss+xml; charset=utf-8"
+	}
+	return "application/atom+xml; charset=utf-8"
+}
+
+// feedCacheControl lets shared caches keep feeds only when links in them
+// come from PUBLIC_URL. Otherwise they come from the request's Host, and
+// a cache shared between hosts could hand one host's links to another.
+func (cfg *apiConfig) feedCacheControl() string {
+	scope := "private"
+	if cfg.publicURL != "" {
+		scope = "public"
+	}
+	return fmt.Sprintf("%s, max-age=%d", scope, int(feedMaxAge.Seconds()))
+}
+
+// feedNotModified answers with a 304 and reports true when the reader's
+// copy of the feed already has tag etag.
+func (cfg *apiConfig) feedNotModified(w http.ResponseWriter, r *http.Request, format, etag string) bool {
+	if !httpcache.NotModified(r, etag, time.Time{}) {
+		return false
+	}
+	w.Header().Set("Cache-Control", cfg.feedCacheControl())
+	httpcache.WriteTag(w, r, feedContentType(format), nil, etag, time.Time{})
+	return true
+}
+
+// writeFeed fills in f's entries from chirps, moving f.Updated up to the
+// newest of them, and sends it with tag etag. There is no Last-Modified:
+// chirps leave a feed without anything's updated_at moving, so only the
+// tag can tell a reader its copy is stale.
+func (cfg *apiConfig) writeFeed(w http.ResponseWriter, r *http.Request, format string, f feed.Feed, chirps []Chirp, etag string) {
+	entries, err := cfg.feedEntries(r.Context(), cfg.baseURL(r), chirps)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+	f.ID = f.Self
+	f.Entries = entries
+	for _, c := range chirps {
+		if c.UpdatedAt.After(f.Updated) {
+			f.Updated = c.UpdatedAt
+		}
+	}
+
+	var body []byte
+	if format == feedRSS {
+		body, err = f.RSS()
+	} else {
+		body, err = f.Atom()
+	}
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	w.Header().Set("Cache-Control", cfg.feedCacheControl())
+	httpcache.WriteTag(w, r, feedContentType(format), body, etag, time.Time{})
+}
+
+// feedEntries turns chirps into feed entries. IDs are urn:uuid: URIs built
+// from the chirp's id, so they never change even if Chirpy moves. A chirp
+// with a content warning uses the warning as its title.
+func (cfg *apiConfig) feedEntries(ctx context.Context, base string, chirps []Chirp) ([]feed.Entry, error) {
+	ids := []uuid.UUID{}
+	for _, c := range chirps {
+		ids = append(ids, c.User_id)
+	}
+	users, err := cfg.dbQueries.GetUsersByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	handles := map[uuid.UUID]string{}
+	for _, u := range users {
+		handles[u.ID] = u.Handle
+	}
+
+	entries := []feed.Entry{}
+	for _, c := range chirps {
+		title := c.Body
+		if c.ContentWarning != "" {
+			title = "CW: " + c.ContentWarning
+		}
+		if utf8.RuneCountInString(title) > maxFeedTitleRune {
+			title = string([]rune(title)[:maxFeedTitleRune-1]) + "…"
+		}
+
+		entry := feed.Entry{
+			ID:        "urn:uuid:" + c.ID.String(),
+			Title:     title,
+			Link:      base + "/api/chirps/" + c.ID.String(),
+			Content:   c.Body,
+			Author:    "@" + handles[c.User_id],
+			Published: c.CreatedAt,
+			Updated:   c.UpdatedAt,
+		}
+		for _, h := range c.Entities.Hashtags {
+			entry.Categories = append(entry.Categories, h.Tag)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
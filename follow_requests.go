@@ -0,0 +1,162 @@
+package main
+
+import (
+	"chirpy/internal/database"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+func (cfg *apiConfig) GetFollowRequests(w http.ResponseWriter, r *http.Request) {
+	userUUID, err := cfg.authenticate(r)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 401, res)
+		return
+	}
+
+	after, limit, err := parsePage(r, newestFirst)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 400, res)
+		return
+	}
+
+	rows, err := cfg.dbQueries.GetFollowRequests(r.Context(), database.GetFollowRequestsParams{
+		UserID:          userUUID,
+		BeforeCreatedAt: after.CreatedAt,
+		BeforeID:        after.ID,
+		PageSize:        limit,
+	})
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	profiles := []Profile{}
+	for _, row := range rows {
+		profiles = append(profiles, Profile{
+			ID:          row.ID,
+			CreatedAt:   row.CreatedAt,
+			Handle:      row.Handle,
+			DisplayName: row.DisplayName,
+			Bio:         row.Bio,
+			AvatarURL:   row.AvatarUrl,
+			Private:     row.Private,
+		})
+	}
+	if len(rows) == int(limit) {
+		last := rows[len(rows)-1]
+		setNextPage(w, r, pageCursor{CreatedAt: last.RequestedAt, ID: last.ID})
+	}
+
+	jsr, err := json.Marshal(profiles)
+	if err != nil {
+		panic(err)
+	}
+	respondWithJson(w, 200, jsr)
+}
+
+func (cfg *apiConfig) ApproveFollowRequest(w http.ResponseWriter, r *http.Request) {
+	userUUID, err := cfg.authenticate(r)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 401, res)
+		return
+	}
+
+	requester, err := cfg.dbQueries.GetUserByHandle(r.Context(), r.PathValue("handle"))
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 404, res)
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	deleted, err := qtx.DeleteFollowRequest(r.Context(), database.DeleteFollowRequestParams{
+		RequesterID: requester.ID,
+		TargetID:    userUUID,
+	})
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+	if deleted == 0 {
+		res := `{"error":"no pending follow request"}`
+		formJsonResponse(w, 404, res)
+		return
+	}
+
+	// FollowUser adds nothing if the two have blocked each other since
+	// the request was made; the request is still used up.
+	followed, err := qtx.FollowUser(r.Context(), database.FollowUserParams{
+		FollowerID: requester.ID,
+		FolloweeID: userUUID,
+	})
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	if followed > 0 {
+		err = cfg.timelines.FollowChanged(r.Context(), requester.ID, userUUID, true)
+	}
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) RejectFollowRequest(w http.ResponseWriter, r *http.Request) {
+	userUUID, err := cfg.authenticate(r)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 401, res)
+		return
+	}
+
+	requester, err := cfg.dbQueries.GetUserByHandle(r.Context(), r.PathValue("handle"))
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 404, res)
+		return
+	}
+
+	deleted, err := cfg.dbQueries.DeleteFollowRequest(r.Context(), database.DeleteFollowRequestParams{
+		RequesterID: requester.ID,
+		TargetID:    userUUID,
+	})
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+	if deleted == 0 {
+		res := `{"error":"no pending follow request"}`
+		formJsonResponse(w, 404, res)
+		return
+	}
+
+	w.WriteHeader(204)
+}
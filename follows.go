@@ -0,0 +1,318 @@
+package main
+
+import (
+	"chirpy/internal/database"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// visibleUser loads the user named in the path, failing with 403 when they
+// are private and the requester isn't one of their approved followers.
+func (cfg *apiConfig) visibleUser(r *http.Request) (database.User, int, error) {
+	viewerUUID, err := cfg.viewer(r)
+	if err != nil {
+		return database.User{}, 401, err
+	}
+
+	target, err := cfg.dbQueries.GetUserByHandle(r.Context(), r.PathValue("handle"))
+	if err != nil {
+		return target, 404, err
+	}
+
+	visible, err := cfg.dbQueries.CanViewAuthor(r.Context(), database.CanViewAuthorParams{
+		AuthorID: target.ID,
+		ViewerID: viewerUUID,
+	})
+	if err != nil {
+		return target, 500, err
+	}
+	if !visible {
+		return target, 403, errors.New("this account is private")
+	}
+	return target, 0, nil
+}
+
+func (cfg *apiConfig) Follow(w http.ResponseWriter, r *http.Request) {
+	userUUID, err := cfg.authenticate(r)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 401, res)
+		return
+	}
+
+	target, err := cfg.dbQueries.GetUserByHandle(r.Context(), r.PathValue("handle"))
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 404, res)
+		return
+	}
+
+	if target.ID == userUUID {
+		res := `{"error":"you can't follow yourself"}`
+		formJsonResponse(w, 400, res)
+		return
+	}
+
+	blocked, err := cfg.dbQueries.BlockedBetween(r.Context(), database.BlockedBetweenParams{
+		UserA: userUUID,
+		UserB: target.ID,
+	})
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+	if blocked {
+		res := `{"error":"you can't follow this user"}`
+		formJsonResponse(w, 403, res)
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	if target.Private {
+		following, err := qtx.IsFollowing(r.Context(), database.IsFollowingParams{
+			FollowerID: userUUID,
+			FolloweeID: target.ID,
+		})
+		if err != nil {
+			res := fmt.Sprintf(`{"error":"%v"}`, err)
+			formJsonResponse(w, 500, res)
+			return
+		}
+		if !following {
+			requested, err := qtx.CreateFollowRequest(r.Context(), database.CreateFollowRequestParams{
+				RequesterID: userUUID,
+				TargetID:    target.ID,
+			})
+			if err != nil {
+				res := fmt.Sprintf(`{"error":"%v"}`, err)
+				formJsonResponse(w, 500, res)
+				return
+			}
+			// Asking again while a request is pending doesn't notify again.
+			var notification database.Notification
+			if requested > 0 {
+				notification, err = qtx.CreateNotification(r.Context(), database.CreateNotificationParams{
+					UserID:  target.ID,
+					ActorID: userUUID,
+					Kind:    notificationFollowRequest,
+				})
+				if err != nil {
+					res := fmt.Sprintf(`{"error":"%v"}`, err)
+					formJsonResponse(w, 500, res)
+					return
+				}
+			}
+			err = tx.Commit()
+			if err != nil {
+				res := fmt.Sprintf(`{"error":"%v"}`, err)
+				formJsonResponse(w, 500, res)
+				return
+			}
+			if requested > 0 {
+				cfg.events.NotificationPublished(notification)
+			}
+			formJsonResponse(w, 202, `{"status":"requested"}`)
+			return
+		}
+	}
+
+	followed, err := qtx.FollowUser(r.Context(), database.FollowUserParams{
+		FollowerID: userUUID,
+		FolloweeID: target.ID,
+	})
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+	var notification database.Notification
+	if followed > 0 {
+		notification, err = qtx.CreateNotification(r.Context(), database.CreateNotificationParams{
+			UserID:  target.ID,
+			ActorID: userUUID,
+			Kind:    notificationFollow,
+		})
+		if err != nil {
+			res := fmt.Sprintf(`{"error":"%v"}`, err)
+			formJsonResponse(w, 500, res)
+			return
+		}
+	}
+	err = tx.Commit()
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+	if followed > 0 {
+		cfg.events.NotificationPublished(notification)
+	}
+
+	err = cfg.timelines.FollowChanged(r.Context(), userUUID, target.ID, true)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) Unfollow(w http.ResponseWriter, r *http.Request) {
+	userUUID, err := cfg.authenticate(r)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 401, res)
+		return
+	}
+
+	target, err := cfg.dbQueries.GetUserByHandle(r.Context(), r.PathValue("handle"))
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 404, res)
+		return
+	}
+
+	err = cfg.dbQueries.UnfollowUser(r.Context(), database.UnfollowUserParams{
+		FollowerID: userUUID,
+		FolloweeID: target.ID,
+	})
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	// Unfollowing also withdraws a request that hasn't been answered yet.
+	_, err = cfg.dbQueries.DeleteFollowRequest(r.Context(), database.DeleteFollowRequestParams{
+		RequesterID: userUUID,
+		TargetID:    target.ID,
+	})
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	err = cfg.timelines.FollowChanged(r.Context(), userUUID, target.ID, false)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) GetFollowers(w http.ResponseWriter, r *http.Request) {
+	target, status, err := cfg.visibleUser(r)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, status, res)
+		return
+	}
+
+	after, limit, err := parsePage(r, newestFirst)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 400, res)
+		return
+	}
+
+	rows, err := cfg.dbQueries.GetFollowers(r.Context(), database.GetFollowersParams{
+		UserID:          target.ID,
+		BeforeCreatedAt: after.CreatedAt,
+		BeforeID:        after.ID,
+		PageSize:        limit,
+	})
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	profiles := []Profile{}
+	for _, row := range rows {
+		profiles = append(profiles, Profile{
+			ID:          row.ID,
+			CreatedAt:   row.CreatedAt,
+			Handle:      row.Handle,
+			DisplayName: row.DisplayName,
+			Bio:         row.Bio,
+			AvatarURL:   row.AvatarUrl,
+			Private:     row.Private,
+		})
+	}
+	if len(rows) == int(limit) {
+		last := rows[len(rows)-1]
+		setNextPage(w, r, pageCursor{CreatedAt: last.FollowedAt, ID: last.ID})
+	}
+
+	jsr, err := json.Marshal(profiles)
+	if err != nil {
+		panic(err)
+	}
+	respondWithJson(w, 200, jsr)
+}
+
+func (cfg *apiConfig) GetFollowing(w http.ResponseWriter, r *http.Request) {
+	target, status, err := cfg.visibleUser(r)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, status, res)
+		return
+	}
+
+	after, limit, err := parsePage(r, newestFirst)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 400, res)
+		return
+	}
+
+	rows, err := cfg.dbQueries.GetFollowing(r.Context(), database.GetFollowingParams{
+		UserID:          target.ID,
+		BeforeCreatedAt: after.CreatedAt,
+		BeforeID:        after.ID,
+		PageSize:        limit,
+	})
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	profiles := []Profile{}
+	for _, row := range rows {
+		profiles = append(profiles, Profile{
+			ID:          row.ID,
+			CreatedAt:   row.CreatedAt,
+			Handle:      row.Handle,
+			DisplayName: row.DisplayName,
+			Bio:         row.Bio,
+			AvatarURL:   row.AvatarUrl,
+			Private:     row.Private,
+		})
+	}
+	if len(rows) == int(limit) {
+		last := rows[len(rows)-1]
+		setNextPage(w, r, pageCursor{CreatedAt: last.FollowedAt, ID: last.ID})
+	}
+
+	jsr, err := json.Marshal(profiles)
+	if err != nil {
+		panic(err)
+	}
+	respondWithJson(w, 200, jsr)
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bytes"
+	"chirpy/internal/database"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	idempotencyKeyTTL = 24 * time.Hour
+	// idempotencyAbandonAfter frees a key whose first request never
+	// finished, such as when the server restarted halfway through it.
+	idempotencyAbandonAfter = time.Minute
+	maxIdempotencyKeyLength = 255
+	maxIdempotentBodyBytes  = 1 << 20
+)
+
+// unreplayedHeaders are response headers that describe the original
+// response's delivery rather than its content, so a replay sets its own.
+var unreplayedHeaders = map[string]bool{
+	"Connection":        true,
+	"Content-Length":    true,
+	"Date":              true,
+	"Transfer-Encoding": true,
+}
+
+// responseRecorder passes a response through while keeping a copy of it.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	header http.Header
+	body   bytes.Buffer
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+		rec.header = rec.Header().Clone()
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.WriteHeader(200)
+	}
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
+
+// idempotent lets clients retry a POST safely with an Idempotency-Key
+// header. The first response for a key is kept for idempotencyKeyTTL and
+// sent back, status, headers and body, for a retry with the same key and
+// body. A retry with a different body is refused with 422, and one that
+// arrives while the first is still running gets 409. Keys belong to the
+// caller and the route, and server errors aren't kept so the request can be
+// tried again.
+//
+// Callers who aren't logged in, such as anyone signing up, all share one
+// set of keys, so theirs must be UUIDs; otherwise two of them picking the
+// same key would get each other's responses.
+func (cfg *apiConfig) idempotent(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("Idempotency-Key")
+		if key == "" {
+			next(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			res := fmt.Sprintf(`{"error":"Idempotency-Key must be at most %d characters"}`, maxIdempotencyKeyLength)
+			formJsonResponse(w, 400, res)
+			return
+		}
+
+		caller := "anonymous"
+		if userUUID, err := cfg.authenticate(r); err == nil {
+			caller = userUUID.String()
+		} else if _, err := uuid.Parse(key); err != nil {
+			formJsonResponse(w, 400, `{"error":"Idempotency-Key must be a UUID when not logged in"}`)
+			return
+		}
+		scope := r.Method + " " + r.URL.Path + " " + caller
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIdempotentBodyBytes))
+		if err != nil {
+			res := fmt.Sprintf(`{"error":"%v"}`, err)
+			formJsonResponse(w, 413, res)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		sum := sha256.Sum256(body)
+		fingerprint := hex.EncodeToString(sum[:])
+
+		now := time.Now()
+		claimed, err := cfg.dbQueries.ClaimIdempotencyKey(r.Context(), database.ClaimIdempotencyKeyParams{
+			Scope:           scope,
+			Key:             key,
+			Fingerprint:     fingerprint,
+			ExpiredBefore:   now.Add(-idempotencyKeyTTL),
+			AbandonedBefore: now.Add(-idempotencyAbandonAfter),
+		})
+		if err != nil {
+			res := fmt.Sprintf(`{"error":"%v"}`, err)
+			formJsonResponse(w, 500, res)
+			return
+		}
+		if claimed == 0 {
+			cfg.replayIdempotent(w, r, scope, key, fingerprint)
+			return
+		}
+
+		rec := &responseRecorder{ResponseWriter: w}
+		next(rec, r)
+
+		// The request's context may be gone by now, but the outcome still
+		// has to be written down.
+		ctx := context.WithoutCancel(r.Context())
+		if rec.status >= 500 || rec.status == 0 {
+			err = cfg.dbQueries.DeleteIdempotencyKey(ctx, database.DeleteIdempotencyKeyParams{
+				Scope: scope,
+				Key:   key,
+			})
+		} else {
+			header := http.Header{}
+			for name, values := range rec.header {
+				if !unreplayedHeaders[name] {
+					header[name] = values
+				}
+			}
+			var headers []byte
+			headers, err = json.Marshal(header)
+			if err != nil {
+				panic(err)
+			}
+			err = cfg.dbQueries.SaveIdempotentResponse(ctx, database.SaveIdempotentResponseParams{
+				Scope:   scope,
+				Key:     key,
+				Status:  sql.NullInt32{Int32: int32(rec.status), Valid: true},
+				Headers: headers,
+				Body:    rec.body.Bytes(),
+			})
+		}
+		if err != nil {
+			log.Printf("idempotency: %v", err)
+		}
+	}
+}
+
+func (cfg *apiConfig) replayIdempotent(w http.ResponseWriter, r *http.Request, scope, key, fingerprint string) {
+	stored, err := cfg.dbQueries.GetIdempotencyKey(r.Context(), database.GetIdempotencyKeyParams{
+		Scope: scope,
+		Key:   key,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		formJsonResponse(w, 409, `{"error":"a request with this Idempotency-Key is still in progress"}`)
+		return
+	}
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+	if stored.Fingerprint != fingerprint {
+		formJsonResponse(w, 422, `{"error":"Idempotency-Key was already used for a different request"}`)
+		return
+	}
+	if !stored.Status.Valid {
+		formJsonResponse(w, 409, `{"error":"a request with this Idempotency-Key is still in progress"}`)
+		return
+	}
+
+	var header http.Header
+	err = json.Unmarshal(stored.Headers, &header)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+	for name, values := range header {
+		w.Header()[name] = values
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(int(stored.Status.Int32))
+	w.Write(stored.Body)
+}
+
+// runIdempotencyPurger forgets keys once they can no longer be replayed.
+func (cfg *apiConfig) runIdempotencyPurger(ctx context.Context) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for {
+		_, err := cfg.dbQueries.PurgeIdempotencyKeys(ctx, time.Now().Add(-idempotencyKeyTTL))
+		if err != nil {
+			log.Printf("idempotency: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestIdempotentReplaysHeaders(t *testing.T) {
+	cfg := newTestConfig(t)
+	_, token := newTestUser(t, cfg, "author")
+	calls := 0
+	handler := cfg.idempotent(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Location", "/api/things/1")
+		respondWithJson(w, 201, []byte(`{"id":1}`))
+	})
+
+	send := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/things", strings.NewReader(`{"name":"thing"}`))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Idempotency-Key", "make-a-thing")
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec
+	}
+
+	first := send()
+	replay := send()
+	if calls != 1 {
+		t.Fatalf("expected the handler to run once, ran %d times", calls)
+	}
+	if replay.Header().Get("Idempotent-Replayed") != "true" {
+		t.Fatal("expected the second response to be a replay")
+	}
+	if replay.Code != first.Code || replay.Body.String() != first.Body.String() {
+		t.Errorf("expected %d %s, got %d %s", first.Code, first.Body, replay.Code, replay.Body)
+	}
+	for _, name := range []string{"Location", "Content-Type"} {
+		if got, want := replay.Header().Get(name), first.Header().Get(name); got != want {
+			t.Errorf("expected %s %q, got %q", name, want, got)
+		}
+	}
+}
+
+func TestIdempotentAnonymousKeysAreUUIDs(t *testing.T) {
+	cfg := newTestConfig(t)
+	handler := cfg.idempotent(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(201)
+	})
+
+	for key, want := range map[string]int{
+		"sign-me-up":     400,
+		uuid.NewString(): 201,
+	} {
+		req := httptest.NewRequest("POST", "/api/users", strings.NewReader(`{}`))
+		req.Header.Set("Idempotency-Key", key)
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		if rec.Code != want {
+			t.Errorf("key %q: expected %d, got %d", key, want, rec.Code)
+		}
+	}
+}
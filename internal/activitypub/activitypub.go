@@ -0,0 +1,174 @@
+package activitypub
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// ContentType is what ActivityPub documents are served and sent as.
+const ContentType = "application/activity+json"
+
+// Public is the special collection that addresses an object to everyone.
+const Public = "https://www.w3.org/ns/activitystreams#Public"
+
+// Context is the JSON-LD context for actors and activities. The security
+// vocabulary is there for publicKey.
+var Context = []string{
+	"https://www.w3.org/ns/activitystreams",
+	"https://w3id.org/security/v1",
+}
+
+type PublicKey struct {
+	ID           string `json:"id"`
+	Owner        string `json:"owner"`
+	PublicKeyPem string `json:"publicKeyPem"`
+}
+
+type Endpoints struct {
+	SharedInbox string `json:"sharedInbox,omitempty"`
+}
+
+type Image struct {
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+
+// Actor is a Person document, for a local account or a fetched remote one.
+type Actor struct {
+	Context           any        `json:"@context,omitempty"`
+	ID                string     `json:"id"`
+	Type              string     `json:"type"`
+	PreferredUsername string     `json:"preferredUsername"`
+	Name              string     `json:"name,omitempty"`
+	Summary           string     `json:"summary,omitempty"`
+	URL               string     `json:"url,omitempty"`
+	Icon              *Image     `json:"icon,omitempty"`
+	Inbox             string     `json:"inbox"`
+	Outbox            string     `json:"outbox,omitempty"`
+	Followers         string     `json:"followers,omitempty"`
+	Endpoints         *Endpoints `json:"endpoints,omitempty"`
+	PublicKey         PublicKey  `json:"publicKey"`
+}
+
+// DeliveryInbox is where activities for this actor should be sent: its
+// server's shared inbox when it has one, so a chirp reaches every follower
+// on a server in one request.
+func (a Actor) DeliveryInbox() string {
+	if a.Endpoints != nil && a.Endpoints.SharedInbox != "" {
+		return a.Endpoints.SharedInbox
+	}
+	return a.Inbox
+}
+
+type Tag struct {
+	Type string `json:"type"`
+	Href string `json:"href"`
+	Name string `json:"name"`
+}
+
+// Note is a chirp. Content is HTML; Summary holds a content warning, which
+// other servers show in front of the content.
+type Note struct {
+	Context      any      `json:"@context,omitempty"`
+	ID           string   `json:"id"`
+	Type         string   `json:"type"`
+	AttributedTo string   `json:"attributedTo"`
+	Content      string   `json:"content"`
+	Summary      string   `json:"summary,omitempty"`
+	Sensitive    bool     `json:"sensitive"`
+	Published    string   `json:"published"`
+	URL          string   `json:"url,omitempty"`
+	To           []string `json:"to"`
+	Cc           []string `json:"cc,omitempty"`
+	Tag          []Tag    `json:"tag,omitempty"`
+}
+
+// Activity is sent to and received from inboxes. Object is whatever was
+// sent: a URI string, or a nested object such as the Follow inside an
+// Accept or an Undo.
+type Activity struct {
+	Context   any             `json:"@context,omitempty"`
+	ID        string          `json:"id"`
+	Type      string          `json:"type"`
+	Actor     string          `json:"actor"`
+	Object    json.RawMessage `json:"object"`
+	Published string          `json:"published,omitempty"`
+	To        []string        `json:"to,omitempty"`
+	Cc        []string        `json:"cc,omitempty"`
+}
+
+// NewActivity builds an activity around object, which may be a URI or any
+// value that marshals to a JSON object.
+func NewActivity(id, typ, actor string, object any) (Activity, error) {
+	raw, err := json.Marshal(object)
+	if err != nil {
+		return Activity{}, err
+	}
+	return Activity{
+		Context: Context,
+		ID:      id,
+		Type:    typ,
+		Actor:   actor,
+		Object:  raw,
+	}, nil
+}
+
+// ObjectID is the id of the activity's object, whether it was sent as a
+// bare URI or embedded.
+func (a Activity) ObjectID() string {
+	var id string
+	if err := json.Unmarshal(a.Object, &id); err == nil {
+		return id
+	}
+	var obj struct {
+		ID string `json:"id"`
+	}
+	json.Unmarshal(a.Object, &obj)
+	return obj.ID
+}
+
+// InnerActivity decodes an embedded activity, such as the Follow an Accept
+// or Undo refers to. It reports false when the object is only a URI.
+func (a Activity) InnerActivity() (Activity, bool) {
+	var inner Activity
+	if err := json.Unmarshal(a.Object, &inner); err != nil || inner.Type == "" {
+		return Activity{}, false
+	}
+	return inner, true
+}
+
+// OrderedCollection is an outbox or followers collection. Items are
+// omitted when only the count is public.
+type OrderedCollection struct {
+	Context      any    `json:"@context,omitempty"`
+	ID           string `json:"id"`
+	Type         string `json:"type"`
+	TotalItems   int    `json:"totalItems"`
+	OrderedItems []any  `json:"orderedItems,omitempty"`
+}
+
+// WebFinger is a JSON Resource Descriptor as served from
+// /.well-known/webfinger.
+type WebFinger struct {
+	Subject string          `json:"subject"`
+	Aliases []string        `json:"aliases,omitempty"`
+	Links   []WebFingerLink `json:"links"`
+}
+
+type WebFingerLink struct {
+	Rel  string `json:"rel"`
+	Type string `json:"type,omitempty"`
+	Href string `json:"href,omitempty"`
+}
+
+// ParseAccount splits "user@example.com", with or without a leading '@' or
+// "acct:", into its user and domain.
+func ParseAccount(account string) (user, domain string, ok bool) {
+	account = strings.TrimPrefix(account, "acct:")
+	account = strings.TrimPrefix(account, "@")
+	user, domain, ok = strings.Cut(account, "@")
+	if !ok || user == "" || domain == "" || strings.ContainsAny(domain, "/?#@") {
+		return "", "", false
+	}
+	return user, domain, true
+}
@@ -0,0 +1,246 @@
+package activitypub
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeInstance is a minimal fediverse server with one account, bob. It
+// serves WebFinger and bob's actor, and its inbox accepts activities only
+// with a valid signature from the actor that sent them.
+type fakeInstance struct {
+	srv    *httptest.Server
+	key    *rsa.PrivateKey
+	client *Client
+
+	mu       sync.Mutex
+	received []Activity
+}
+
+func newFakeInstance(t *testing.T) *fakeInstance {
+	t.Helper()
+	key, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeInstance{key: key}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/webfinger", f.webfinger)
+	mux.HandleFunc("GET /users/bob", f.actor)
+	mux.HandleFunc("POST /inbox", f.inbox)
+	f.srv = httptest.NewTLSServer(mux)
+	t.Cleanup(f.srv.Close)
+	return f
+}
+
+func (f *fakeInstance) actorID() string {
+	return f.srv.URL + "/users/bob"
+}
+
+func (f *fakeInstance) webfinger(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("resource") != "acct:bob@"+f.srv.Listener.Addr().String() {
+		http.NotFound(w, r)
+		return
+	}
+	json.NewEncoder(w).Encode(WebFinger{
+		Subject: r.URL.Query().Get("resource"),
+		Links:   []WebFingerLink{{Rel: "self", Type: ContentType, Href: f.actorID()}},
+	})
+}
+
+func (f *fakeInstance) actor(w http.ResponseWriter, r *http.Request) {
+	pem, _ := EncodePublicKey(&f.key.PublicKey)
+	w.Header().Set("Content-Type", ContentType)
+	json.NewEncoder(w).Encode(Actor{
+		Context:           Context,
+		ID:                f.actorID(),
+		Type:              "Person",
+		PreferredUsername: "bob",
+		Inbox:             f.srv.URL + "/inbox",
+		Endpoints:         &Endpoints{SharedInbox: f.srv.URL + "/inbox"},
+		PublicKey:         PublicKey{ID: f.actorID() + "#main-key", Owner: f.actorID(), PublicKeyPem: pem},
+	})
+}
+
+func (f *fakeInstance) inbox(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(io.LimitReader(r.Body, MaxDocumentBytes))
+	var owner string
+	_, err := Verify(r, body, func(keyID string) (*rsa.PublicKey, error) {
+		actor, err := f.client.FetchActor(r.Context(), keyID)
+		if err != nil {
+			return nil, err
+		}
+		owner = actor.ID
+		return ParsePublicKey(actor.PublicKey.PublicKeyPem)
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	var activity Activity
+	if err := json.Unmarshal(body, &activity); err != nil || activity.Actor != owner {
+		http.Error(w, "activity isn't from the signer", http.StatusUnauthorized)
+		return
+	}
+	f.mu.Lock()
+	f.received = append(f.received, activity)
+	f.mu.Unlock()
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// trustingClient trusts the test certificates of every instance.
+func trustingClient(instances ...*fakeInstance) *http.Client {
+	pool := x509.NewCertPool()
+	for _, f := range instances {
+		pool.AddCert(f.srv.Certificate())
+	}
+	return &http.Client{
+		Timeout:   5 * time.Second,
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}},
+	}
+}
+
+func TestFederationWithFakeRemote(t *testing.T) {
+	local := newFakeInstance(t)
+	remote := newFakeInstance(t)
+	client := NewClient(trustingClient(local, remote), "chirpy-test")
+	local.client, remote.client = client, client
+	ctx := context.Background()
+
+	actorID, err := client.Finger(ctx, "@bob@"+remote.srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actorID != remote.actorID() {
+		t.Fatalf("expected WebFinger to find %s, got %s", remote.actorID(), actorID)
+	}
+	actor, err := client.FetchActor(ctx, actorID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	follow, err := NewActivity(local.srv.URL+"/follows/1", "Follow", local.actorID(), actor.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := json.Marshal(follow)
+	err = client.Deliver(ctx, actor.DeliveryInbox(), body, local.actorID()+"#main-key", local.key)
+	if err != nil {
+		t.Fatalf("expected the remote to accept a signed Follow: %v", err)
+	}
+
+	accept, err := NewActivity(remote.srv.URL+"/accepts/1", "Accept", remote.actorID(), follow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ = json.Marshal(accept)
+	err = client.Deliver(ctx, local.srv.URL+"/inbox", body, remote.actorID()+"#main-key", remote.key)
+	if err != nil {
+		t.Fatalf("expected the Accept to be delivered back: %v", err)
+	}
+
+	if len(remote.received) != 1 || remote.received[0].Type != "Follow" || remote.received[0].ObjectID() != actor.ID {
+		t.Errorf("expected the remote to have one Follow of bob, got %+v", remote.received)
+	}
+	if len(local.received) != 1 {
+		t.Fatalf("expected one Accept, got %d", len(local.received))
+	}
+	inner, ok := local.received[0].InnerActivity()
+	if !ok || inner.Type != "Follow" || inner.ID != follow.ID {
+		t.Errorf("expected the Accept to carry the Follow, got %+v", inner)
+	}
+
+	// Signing as someone else, or with the wrong key, is turned away.
+	impostor, _ := GenerateKey()
+	err = client.Deliver(ctx, remote.srv.URL+"/inbox", body, local.actorID()+"#main-key", impostor)
+	var status *StatusError
+	if !errors.As(err, &status) || status.Code != http.StatusUnauthorized || !status.Permanent() {
+		t.Errorf("expected 401 for a forged signature, got %v", err)
+	}
+}
+
+func TestFetchActorRejectsMismatchedID(t *testing.T) {
+	remote := newFakeInstance(t)
+	client := NewClient(trustingClient(remote), "chirpy-test")
+	remote.client = client
+
+	_, err := client.FetchActor(context.Background(), remote.srv.URL+"/users/bob?alias=1")
+	if err == nil || !strings.Contains(err.Error(), "claims to be") {
+		t.Errorf("expected an actor served from another address to be refused, got %v", err)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	key, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	lookup := func(keyID string) (*rsa.PublicKey, error) {
+		if keyID != "https://a.example/users/alice#main-key" {
+			return nil, errors.New("unknown key")
+		}
+		return &key.PublicKey, nil
+	}
+	signed := func(body []byte, date string) *http.Request {
+		req := httptest.NewRequest("POST", "https://b.example/inbox", bytes.NewReader(body))
+		if date != "" {
+			req.Header.Set("Date", date)
+		}
+		if err := Sign(req, body, "https://a.example/users/alice#main-key", key); err != nil {
+			t.Fatal(err)
+		}
+		return req
+	}
+
+	body := []byte(`{"type":"Follow"}`)
+	if _, err := Verify(signed(body, ""), body, lookup); err != nil {
+		t.Errorf("expected a fresh signature to verify: %v", err)
+	}
+
+	if _, err := Verify(signed(body, ""), []byte(`{"type":"Delete"}`), lookup); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("expected a changed body to fail, got %v", err)
+	}
+
+	old := time.Now().Add(-2 * MaxClockSkew).UTC().Format(http.TimeFormat)
+	if _, err := Verify(signed(body, old), body, lookup); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("expected a stale date to fail, got %v", err)
+	}
+
+	req := signed(body, "")
+	req.URL.Path = "/other/inbox"
+	if _, err := Verify(req, body, lookup); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("expected a different target to fail, got %v", err)
+	}
+
+	req = signed(body, "")
+	req.Header.Set("Signature", strings.Replace(req.Header.Get("Signature"), ` digest"`, `"`, 1))
+	if _, err := Verify(req, body, lookup); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("expected a signature that leaves out the digest to fail, got %v", err)
+	}
+}
+
+func TestParseAccount(t *testing.T) {
+	for _, s := range []string{"bob@example.com", "@bob@example.com", "acct:bob@example.com"} {
+		user, domain, ok := ParseAccount(s)
+		if !ok || user != "bob" || domain != "example.com" {
+			t.Errorf("%q: got %q %q %v", s, user, domain, ok)
+		}
+	}
+	for _, s := range []string{"bob", "@bob", "bob@", "bob@example.com/x"} {
+		if _, _, ok := ParseAccount(s); ok {
+			t.Errorf("expected %q to be refused", s)
+		}
+	}
+}
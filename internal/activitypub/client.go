@@ -0,0 +1,157 @@
+package activitypub
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// MaxDocumentBytes caps how much of a remote document or inbox post is
+// read.
+const MaxDocumentBytes = 1 << 20
+
+// Doer sends HTTP requests. Federation talks to servers chosen by whoever
+// is on the other end, so the Doer should refuse private addresses.
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// StatusError is a non-2xx answer from a remote server.
+type StatusError struct {
+	URL  string
+	Code int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s returned %d", e.URL, e.Code)
+}
+
+// Permanent reports whether retrying the request can't help, as with 404
+// or 410, unlike a rate limit or a server error.
+func (e *StatusError) Permanent() bool {
+	return e.Code >= 400 && e.Code < 500 && e.Code != 408 && e.Code != 429
+}
+
+type Client struct {
+	doer      Doer
+	userAgent string
+}
+
+func NewClient(doer Doer, userAgent string) *Client {
+	return &Client{doer: doer, userAgent: userAgent}
+}
+
+// Deliver posts activity to inbox, signed with key.
+func (c *Client) Deliver(ctx context.Context, inbox string, activity []byte, keyID string, key *rsa.PrivateKey) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", inbox, bytes.NewReader(activity))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", ContentType)
+	req.Header.Set("User-Agent", c.userAgent)
+	err = Sign(req, activity, keyID, key)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.doer.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, MaxDocumentBytes))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &StatusError{URL: inbox, Code: resp.StatusCode}
+	}
+	return nil
+}
+
+// FetchActor reads the actor at uri. The key id in a signature usually
+// names a fragment of the actor, which is dropped before fetching. The
+// actor must say it lives at the address it was fetched from, so one
+// server can't speak for another's accounts.
+func (c *Client) FetchActor(ctx context.Context, uri string) (Actor, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return Actor{}, err
+	}
+	u.Fragment = ""
+
+	var actor Actor
+	err = c.get(ctx, u.String(), ContentType+`, application/ld+json; profile="https://www.w3.org/ns/activitystreams"`, &actor)
+	if err != nil {
+		return Actor{}, err
+	}
+	if actor.ID != u.String() {
+		return Actor{}, fmt.Errorf("actor fetched from %s claims to be %s", u, actor.ID)
+	}
+	if actor.Inbox == "" || actor.PublicKey.PublicKeyPem == "" {
+		return Actor{}, fmt.Errorf("actor %s has no inbox or public key", actor.ID)
+	}
+	if !sameHost(actor.ID, actor.Inbox) || (actor.Endpoints != nil && actor.Endpoints.SharedInbox != "" && !sameHost(actor.ID, actor.Endpoints.SharedInbox)) {
+		return Actor{}, fmt.Errorf("actor %s has an inbox on another server", actor.ID)
+	}
+	if actor.PublicKey.Owner != actor.ID {
+		return Actor{}, fmt.Errorf("actor %s publishes a key it doesn't own", actor.ID)
+	}
+	return actor, nil
+}
+
+// Finger looks up the actor URI for an account like "user@example.com"
+// with WebFinger.
+func (c *Client) Finger(ctx context.Context, account string) (string, error) {
+	user, domain, ok := ParseAccount(account)
+	if !ok {
+		return "", fmt.Errorf("%q is not an account like user@example.com", account)
+	}
+	q := url.Values{"resource": {"acct:" + user + "@" + domain}}
+	var jrd WebFinger
+	err := c.get(ctx, "https://"+domain+"/.well-known/webfinger?"+q.Encode(), "application/jrd+json, application/json", &jrd)
+	if err != nil {
+		return "", err
+	}
+	for _, link := range jrd.Links {
+		if link.Rel == "self" && (link.Type == ContentType || strings.HasPrefix(link.Type, "application/ld+json")) && link.Href != "" {
+			return link.Href, nil
+		}
+	}
+	return "", errors.New("account has no ActivityPub actor")
+}
+
+func (c *Client) get(ctx context.Context, uri, accept string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", uri, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", accept)
+	req.Header.Set("User-Agent", c.userAgent)
+
+	resp, err := c.doer.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &StatusError{URL: uri, Code: resp.StatusCode}
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, MaxDocumentBytes)).Decode(v)
+}
+
+func sameHost(a, b string) bool {
+	ua, err := url.Parse(a)
+	if err != nil {
+		return false
+	}
+	ub, err := url.Parse(b)
+	if err != nil {
+		return false
+	}
+	return ua.Host != "" && ua.Host == ub.Host
+}
@@ -0,0 +1,193 @@
+package activitypub
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// MaxClockSkew is how far a signed request's Date may be from now.
+const MaxClockSkew = time.Hour
+
+// signedHeaders are the headers every signature covers. Requests with a
+// body sign its Digest too.
+var signedHeaders = []string{"(request-target)", "host", "date"}
+
+var ErrBadSignature = errors.New("invalid HTTP signature")
+
+// GenerateKey makes a key pair for an actor.
+func GenerateKey() (*rsa.PrivateKey, error) {
+	return rsa.GenerateKey(rand.Reader, 2048)
+}
+
+func EncodePrivateKey(key *rsa.PrivateKey) string {
+	der := x509.MarshalPKCS1PrivateKey(key)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: der}))
+}
+
+// EncodePublicKey writes key in the PKIX form actor documents carry.
+func EncodePublicKey(key *rsa.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})), nil
+}
+
+func ParsePrivateKey(s string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(s))
+	if block == nil {
+		return nil, errors.New("no PEM data in private key")
+	}
+	return x509.ParsePKCS1PrivateKey(block.Bytes)
+}
+
+// ParsePublicKey reads an RSA key in PKIX or PKCS #1 form, since servers
+// publish either.
+func ParsePublicKey(s string) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode([]byte(s))
+	if block == nil {
+		return nil, errors.New("no PEM data in public key")
+	}
+	if key, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := parsed.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("public key is not an RSA key")
+	}
+	return key, nil
+}
+
+// Sign signs req with key as draft-cavage-http-signatures-12 describes and
+// Mastodon expects, adding Date and, for a body, Digest. A Date already on
+// the request is kept.
+func Sign(req *http.Request, body []byte, keyID string, key *rsa.PrivateKey) error {
+	if req.Header.Get("Date") == "" {
+		req.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+	}
+	headers := signedHeaders
+	if body != nil {
+		req.Header.Set("Digest", digest(body))
+		headers = append(headers[:len(headers):len(headers)], "digest")
+	}
+
+	hashed := sha256.Sum256([]byte(signingString(req, headers)))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hashed[:])
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Signature", fmt.Sprintf(`keyId="%s",algorithm="rsa-sha256",headers="%s",signature="%s"`,
+		keyID, strings.Join(headers, " "), base64.StdEncoding.EncodeToString(sig)))
+	return nil
+}
+
+// Verify checks the Signature header on an incoming request and returns
+// the keyId it was signed with. lookup finds the public key for a keyId,
+// usually by fetching the actor that owns it. The signature must cover the
+// request target, host and date, and the digest when there is a body, and
+// the date must be within MaxClockSkew of now.
+func Verify(req *http.Request, body []byte, lookup func(keyID string) (*rsa.PublicKey, error)) (string, error) {
+	params := parseSignature(req.Header.Get("Signature"))
+	keyID, headerList, sig64 := params["keyId"], params["headers"], params["signature"]
+	if keyID == "" || sig64 == "" {
+		return "", fmt.Errorf("%w: missing keyId or signature", ErrBadSignature)
+	}
+	if headerList == "" {
+		headerList = "date"
+	}
+	headers := strings.Fields(strings.ToLower(headerList))
+
+	required := signedHeaders
+	if len(body) > 0 {
+		required = append(required[:len(required):len(required)], "digest")
+	}
+	for _, h := range required {
+		if !contains(headers, h) {
+			return keyID, fmt.Errorf("%w: %s is not signed", ErrBadSignature, h)
+		}
+	}
+
+	date, err := http.ParseTime(req.Header.Get("Date"))
+	if err != nil {
+		return keyID, fmt.Errorf("%w: bad date", ErrBadSignature)
+	}
+	if skew := time.Since(date); skew > MaxClockSkew || skew < -MaxClockSkew {
+		return keyID, fmt.Errorf("%w: date is too far from now", ErrBadSignature)
+	}
+	if contains(headers, "digest") && req.Header.Get("Digest") != digest(body) {
+		return keyID, fmt.Errorf("%w: digest doesn't match body", ErrBadSignature)
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(sig64)
+	if err != nil {
+		return keyID, fmt.Errorf("%w: %v", ErrBadSignature, err)
+	}
+	key, err := lookup(keyID)
+	if err != nil {
+		return keyID, err
+	}
+	hashed := sha256.Sum256([]byte(signingString(req, headers)))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, hashed[:], sig); err != nil {
+		return keyID, fmt.Errorf("%w: %v", ErrBadSignature, err)
+	}
+	return keyID, nil
+}
+
+func signingString(req *http.Request, headers []string) string {
+	lines := []string{}
+	for _, h := range headers {
+		var value string
+		switch h {
+		case "(request-target)":
+			value = strings.ToLower(req.Method) + " " + req.URL.RequestURI()
+		case "host":
+			value = req.Host
+			if value == "" {
+				value = req.URL.Host
+			}
+		default:
+			value = strings.Join(req.Header.Values(h), ", ")
+		}
+		lines = append(lines, h+": "+value)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func parseSignature(header string) map[string]string {
+	params := map[string]string{}
+	for _, part := range strings.Split(header, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if ok {
+			params[k] = strings.Trim(v, `"`)
+		}
+	}
+	return params
+}
+
+func digest(body []byte) string {
+	sum := sha256.Sum256(body)
+	return "SHA-256=" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,112 @@
+package blobstore
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type blobStore interface {
+	Put(ctx context.Context, key, contentType string, body []byte) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+func testRoundTrip(t *testing.T, store blobStore) {
+	ctx := context.Background()
+	if err := store.Put(ctx, "media/a.png", "image/png", []byte("png bytes")); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := store.Get(ctx, "media/a.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := io.ReadAll(r)
+	r.Close()
+	if string(got) != "png bytes" {
+		t.Errorf("expected the stored bytes back, got %q", got)
+	}
+
+	if err := store.Delete(ctx, "media/a.png"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Get(ctx, "media/a.png"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound after delete, got %v", err)
+	}
+	if err := store.Delete(ctx, "media/a.png"); err != nil {
+		t.Errorf("expected deleting a missing blob to succeed, got %v", err)
+	}
+}
+
+func TestLocal(t *testing.T) {
+	store := NewLocal(t.TempDir())
+	testRoundTrip(t, store)
+
+	if err := store.Put(context.Background(), "../escape", "text/plain", nil); err == nil {
+		t.Errorf("expected a key outside the directory to be refused")
+	}
+}
+
+// s3Stub is just enough of an S3-compatible server to store objects. It
+// checks that requests are signed and that the signed payload hash matches
+// the body.
+type s3Stub struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	t       *testing.T
+}
+
+func (s *s3Stub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=minio/") || !strings.Contains(auth, "/us-east-1/s3/aws4_request") {
+		s.t.Errorf("unexpected Authorization %q", auth)
+		w.WriteHeader(403)
+		return
+	}
+	body, _ := io.ReadAll(r.Body)
+	sum := sha256.Sum256(body)
+	if r.Header.Get("X-Amz-Content-Sha256") != hex.EncodeToString(sum[:]) {
+		s.t.Errorf("payload hash doesn't match the body")
+		w.WriteHeader(400)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch r.Method {
+	case http.MethodPut:
+		s.objects[r.URL.Path] = body
+	case http.MethodGet:
+		object, ok := s.objects[r.URL.Path]
+		if !ok {
+			w.WriteHeader(404)
+			return
+		}
+		w.Write(object)
+	case http.MethodDelete:
+		delete(s.objects, r.URL.Path)
+		w.WriteHeader(204)
+	}
+}
+
+func TestS3(t *testing.T) {
+	stub := &s3Stub{objects: map[string][]byte{}, t: t}
+	server := httptest.NewServer(stub)
+	defer server.Close()
+
+	store := NewS3(server.URL, "chirpy", "us-east-1", "minio", "minio123")
+	testRoundTrip(t, store)
+
+	store.Put(context.Background(), "media/b.png", "image/png", []byte("b"))
+	if _, ok := stub.objects["/chirpy/media/b.png"]; !ok {
+		t.Errorf("expected the object to be stored path-style in the bucket")
+	}
+}
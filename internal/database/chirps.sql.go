@@ -7,29 +7,41 @@ package database
 
 import (
 	"context"
+	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const createChirp = `-- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, visibility, content_warning)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3,
+    $4
 )
-RETURNING id, created_at, updated_at, body, user_id
+RETURNING id, created_at, updated_at, body, user_id, visibility, content_warning, search, deleted_at, deleted_by
 `
 
 type CreateChirpParams struct {
-	Body   string    `json:"body"`
-	UserID uuid.UUID `json:"user_id"`
+	Body           string    `json:"body"`
+	UserID         uuid.UUID `json:"user_id"`
+	Visibility     string    `json:"visibility"`
+	ContentWarning string    `json:"content_warning"`
 }
 
 func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, createChirp, arg.Body, arg.UserID)
+	row := q.db.QueryRowContext(ctx, createChirp,
+		arg.Body,
+		arg.UserID,
+		arg.Visibility,
+		arg.ContentWarning,
+	)
 	var i Chirp
 	err := row.Scan(
 		&i.ID,
@@ -37,25 +49,27 @@ func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.Visibility,
+		&i.ContentWarning,
+		&i.Search,
+		&i.DeletedAt,
+		&i.DeletedBy,
 	)
 	return i, err
 }
 
-const deleteChirp = `-- name: DeleteChirp :exec
-delete from chirps where id = $1
+const getChirp = `-- name: GetChirp :one
+select id, created_at, updated_at, body, user_id, visibility, content_warning, search, deleted_at, deleted_by from chirps
+where id = $1 and chirp_visible_to(id, $2)
 `
 
-func (q *Queries) DeleteChirp(ctx context.Context, id uuid.UUID) error {
-	_, err := q.db.ExecContext(ctx, deleteChirp, id)
-	return err
+type GetChirpParams struct {
+	ID       uuid.UUID `json:"id"`
+	ViewerID uuid.UUID `json:"viewer_id"`
 }
 
-const getChirp = `-- name: GetChirp :one
-select id, created_at, updated_at, body, user_id from chirps where id = $1
-`
-
-func (q *Queries) GetChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, getChirp, id)
+func (q *Queries) GetChirp(ctx context.Context, arg GetChirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, getChirp, arg.ID, arg.ViewerID)
 	var i Chirp
 	err := row.Scan(
 		&i.ID,
@@ -63,12 +77,17 @@ func (q *Queries) GetChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.Visibility,
+		&i.ContentWarning,
+		&i.Search,
+		&i.DeletedAt,
+		&i.DeletedBy,
 	)
 	return i, err
 }
 
 const getChirpOwner = `-- name: GetChirpOwner :one
-select user_id from chirps where id = $1
+select user_id from chirps where id = $1 and deleted_at is null
 `
 
 func (q *Queries) GetChirpOwner(ctx context.Context, id uuid.UUID) (uuid.UUID, error) {
@@ -79,11 +98,268 @@ func (q *Queries) GetChirpOwner(ctx context.Context, id uuid.UUID) (uuid.UUID, e
 }
 
 const getChirps = `-- name: GetChirps :many
-select id, created_at, updated_at, body, user_id from chirps
+select id, created_at, updated_at, body, user_id, visibility, content_warning, search, deleted_at, deleted_by from chirps
+where chirp_visible_to(id, $1)
+and (visibility <> 'unlisted' or user_id = $1)
+and user_id not in (select muted_id from mutes where muter_id = $1)
+and (
+    $2::uuid is null
+    or (
+        user_id = $2
+        and id not in (select chirp_id from pinned_chirps where user_id = $2)
+    )
+)
+and (created_at, id) > ($3::timestamp, $4::uuid)
+order by created_at, id
+limit $5
+`
+
+type GetChirpsParams struct {
+	ViewerID       uuid.UUID     `json:"viewer_id"`
+	AuthorID       uuid.NullUUID `json:"author_id"`
+	AfterCreatedAt time.Time     `json:"after_created_at"`
+	AfterID        uuid.UUID     `json:"after_id"`
+	PageSize       int32         `json:"page_size"`
+}
+
+func (q *Queries) GetChirps(ctx context.Context, arg GetChirpsParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirps,
+		arg.ViewerID,
+		arg.AuthorID,
+		arg.AfterCreatedAt,
+		arg.AfterID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.Visibility,
+			&i.ContentWarning,
+			&i.Search,
+			&i.DeletedAt,
+			&i.DeletedBy,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpsByHashtag = `-- name: GetChirpsByHashtag :many
+select id, created_at, updated_at, body, user_id, visibility, content_warning, search, deleted_at, deleted_by from chirps
+where id in (select chirp_id from chirp_hashtags where tag = $1)
+and chirp_visible_to(id, $2)
+and (visibility <> 'unlisted' or user_id = $2)
+and user_id not in (select muted_id from mutes where muter_id = $2)
+and (created_at, id) < ($3::timestamp, $4::uuid)
+order by created_at desc, id desc
+limit $5
+`
+
+type GetChirpsByHashtagParams struct {
+	Tag             string    `json:"tag"`
+	ViewerID        uuid.UUID `json:"viewer_id"`
+	BeforeCreatedAt time.Time `json:"before_created_at"`
+	BeforeID        uuid.UUID `json:"before_id"`
+	PageSize        int32     `json:"page_size"`
+}
+
+func (q *Queries) GetChirpsByHashtag(ctx context.Context, arg GetChirpsByHashtagParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsByHashtag,
+		arg.Tag,
+		arg.ViewerID,
+		arg.BeforeCreatedAt,
+		arg.BeforeID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.Visibility,
+			&i.ContentWarning,
+			&i.Search,
+			&i.DeletedAt,
+			&i.DeletedBy,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpsByIDs = `-- name: GetChirpsByIDs :many
+select id, created_at, updated_at, body, user_id, visibility, content_warning, search, deleted_at, deleted_by from chirps
+where id = ANY($1::uuid[]) and chirp_visible_to(id, $2)
+`
+
+type GetChirpsByIDsParams struct {
+	Ids      []uuid.UUID `json:"ids"`
+	ViewerID uuid.UUID   `json:"viewer_id"`
+}
+
+func (q *Queries) GetChirpsByIDs(ctx context.Context, arg GetChirpsByIDsParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsByIDs, pq.Array(arg.Ids), arg.ViewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.Visibility,
+			&i.ContentWarning,
+			&i.Search,
+			&i.DeletedAt,
+			&i.DeletedBy,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getDeletedChirp = `-- name: GetDeletedChirp :one
+select id, created_at, updated_at, body, user_id, visibility, content_warning, search, deleted_at, deleted_by from chirps where id = $1 and deleted_at is not null
+`
+
+func (q *Queries) GetDeletedChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, getDeletedChirp, id)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.Visibility,
+		&i.ContentWarning,
+		&i.Search,
+		&i.DeletedAt,
+		&i.DeletedBy,
+	)
+	return i, err
+}
+
+const getHashtagFeedVersions = `-- name: GetHashtagFeedVersions :many
+select chirps.id, chirps.updated_at, users.handle from chirps
+join users on users.id = chirps.user_id
+where chirps.id in (select chirp_id from chirp_hashtags where tag = $1)
+and chirp_visible_to(chirps.id, $2)
+and (chirps.visibility <> 'unlisted' or chirps.user_id = $2)
+and chirps.user_id not in (select muted_id from mutes where muter_id = $2)
+order by chirps.created_at desc, chirps.id desc
+limit $3
+`
+
+type GetHashtagFeedVersionsParams struct {
+	Tag      string    `json:"tag"`
+	ViewerID uuid.UUID `json:"viewer_id"`
+	PageSize int32     `json:"page_size"`
+}
+
+type GetHashtagFeedVersionsRow struct {
+	ID        uuid.UUID `json:"id"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Handle    string    `json:"handle"`
+}
+
+func (q *Queries) GetHashtagFeedVersions(ctx context.Context, arg GetHashtagFeedVersionsParams) ([]GetHashtagFeedVersionsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getHashtagFeedVersions, arg.Tag, arg.ViewerID, arg.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetHashtagFeedVersionsRow
+	for rows.Next() {
+		var i GetHashtagFeedVersionsRow
+		if err := rows.Scan(&i.ID, &i.UpdatedAt, &i.Handle); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getHomeTimeline = `-- name: GetHomeTimeline :many
+select id, created_at, updated_at, body, user_id, visibility, content_warning, search, deleted_at, deleted_by from chirps
+where (
+    user_id = $1
+    or user_id in (select followee_id from follows where follower_id = $1)
+)
+and chirp_visible_to(id, $1)
+and user_id not in (select muted_id from mutes where muter_id = $1)
+and (created_at, id) < ($2::timestamp, $3::uuid)
+order by created_at desc, id desc
+limit $4
 `
 
-func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, getChirps)
+type GetHomeTimelineParams struct {
+	ViewerID        uuid.UUID `json:"viewer_id"`
+	BeforeCreatedAt time.Time `json:"before_created_at"`
+	BeforeID        uuid.UUID `json:"before_id"`
+	PageSize        int32     `json:"page_size"`
+}
+
+func (q *Queries) GetHomeTimeline(ctx context.Context, arg GetHomeTimelineParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getHomeTimeline,
+		arg.ViewerID,
+		arg.BeforeCreatedAt,
+		arg.BeforeID,
+		arg.PageSize,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -97,6 +373,218 @@ func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.Visibility,
+			&i.ContentWarning,
+			&i.Search,
+			&i.DeletedAt,
+			&i.DeletedBy,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUserChirps = `-- name: GetUserChirps :many
+select id, created_at, updated_at, body, user_id, visibility, content_warning, search, deleted_at, deleted_by from chirps
+where user_id = $1
+and chirp_visible_to(id, $2)
+and (created_at, id) < ($3::timestamp, $4::uuid)
+order by created_at desc, id desc
+limit $5
+`
+
+type GetUserChirpsParams struct {
+	AuthorID        uuid.UUID `json:"author_id"`
+	ViewerID        uuid.UUID `json:"viewer_id"`
+	BeforeCreatedAt time.Time `json:"before_created_at"`
+	BeforeID        uuid.UUID `json:"before_id"`
+	PageSize        int32     `json:"page_size"`
+}
+
+func (q *Queries) GetUserChirps(ctx context.Context, arg GetUserChirpsParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getUserChirps,
+		arg.AuthorID,
+		arg.ViewerID,
+		arg.BeforeCreatedAt,
+		arg.BeforeID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.Visibility,
+			&i.ContentWarning,
+			&i.Search,
+			&i.DeletedAt,
+			&i.DeletedBy,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUserFeedVersions = `-- name: GetUserFeedVersions :many
+select id, updated_at from chirps
+where user_id = $1
+and chirp_visible_to(id, $2)
+order by created_at desc, id desc
+limit $3
+`
+
+type GetUserFeedVersionsParams struct {
+	AuthorID uuid.UUID `json:"author_id"`
+	ViewerID uuid.UUID `json:"viewer_id"`
+	PageSize int32     `json:"page_size"`
+}
+
+type GetUserFeedVersionsRow struct {
+	ID        uuid.UUID `json:"id"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (q *Queries) GetUserFeedVersions(ctx context.Context, arg GetUserFeedVersionsParams) ([]GetUserFeedVersionsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getUserFeedVersions, arg.AuthorID, arg.ViewerID, arg.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetUserFeedVersionsRow
+	for rows.Next() {
+		var i GetUserFeedVersionsRow
+		if err := rows.Scan(&i.ID, &i.UpdatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const purgeDeletedChirps = `-- name: PurgeDeletedChirps :execrows
+delete from chirps where deleted_at < $1
+`
+
+func (q *Queries) PurgeDeletedChirps(ctx context.Context, deletedAt sql.NullTime) (int64, error) {
+	result, err := q.db.ExecContext(ctx, purgeDeletedChirps, deletedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const restoreChirp = `-- name: RestoreChirp :one
+update chirps set deleted_at = null, deleted_by = null
+where id = $1 and deleted_at is not null
+RETURNING id, created_at, updated_at, body, user_id, visibility, content_warning, search, deleted_at, deleted_by
+`
+
+func (q *Queries) RestoreChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, restoreChirp, id)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.Visibility,
+		&i.ContentWarning,
+		&i.Search,
+		&i.DeletedAt,
+		&i.DeletedBy,
+	)
+	return i, err
+}
+
+const searchChirps = `-- name: SearchChirps :many
+select chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.visibility, chirps.content_warning, chirps.search, chirps.deleted_at, chirps.deleted_by, ts_rank(search, to_tsquery('english', $1))::real as rank
+from chirps
+where search @@ to_tsquery('english', $1)
+and ($2::uuid is null or user_id = $2)
+and chirp_visible_to(id, $3)
+and (visibility <> 'unlisted' or user_id = $3)
+and user_id not in (select muted_id from mutes where muter_id = $3)
+and (ts_rank(search, to_tsquery('english', $1))::real, created_at, id)
+    < ($4::real, $5::timestamp, $6::uuid)
+order by rank desc, created_at desc, id desc
+limit $7
+`
+
+type SearchChirpsParams struct {
+	Query           string        `json:"query"`
+	AuthorID        uuid.NullUUID `json:"author_id"`
+	ViewerID        uuid.UUID     `json:"viewer_id"`
+	BeforeRank      float32       `json:"before_rank"`
+	BeforeCreatedAt time.Time     `json:"before_created_at"`
+	BeforeID        uuid.UUID     `json:"before_id"`
+	PageSize        int32         `json:"page_size"`
+}
+
+type SearchChirpsRow struct {
+	Chirp Chirp   `json:"chirp"`
+	Rank  float32 `json:"rank"`
+}
+
+func (q *Queries) SearchChirps(ctx context.Context, arg SearchChirpsParams) ([]SearchChirpsRow, error) {
+	rows, err := q.db.QueryContext(ctx, searchChirps,
+		arg.Query,
+		arg.AuthorID,
+		arg.ViewerID,
+		arg.BeforeRank,
+		arg.BeforeCreatedAt,
+		arg.BeforeID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchChirpsRow
+	for rows.Next() {
+		var i SearchChirpsRow
+		if err := rows.Scan(
+			&i.Chirp.ID,
+			&i.Chirp.CreatedAt,
+			&i.Chirp.UpdatedAt,
+			&i.Chirp.Body,
+			&i.Chirp.UserID,
+			&i.Chirp.Visibility,
+			&i.Chirp.ContentWarning,
+			&i.Chirp.Search,
+			&i.Chirp.DeletedAt,
+			&i.Chirp.DeletedBy,
+			&i.Rank,
 		); err != nil {
 			return nil, err
 		}
@@ -110,3 +598,21 @@ func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
 	}
 	return items, nil
 }
+
+const softDeleteChirp = `-- name: SoftDeleteChirp :execrows
+update chirps set deleted_at = NOW(), deleted_by = $1
+where id = $2 and deleted_at is null
+`
+
+type SoftDeleteChirpParams struct {
+	DeletedBy uuid.NullUUID `json:"deleted_by"`
+	ID        uuid.UUID     `json:"id"`
+}
+
+func (q *Queries) SoftDeleteChirp(ctx context.Context, arg SoftDeleteChirpParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, softDeleteChirp, arg.DeletedBy, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
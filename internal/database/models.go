@@ -6,17 +6,263 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+type ActorKey struct {
+	UserID        uuid.UUID `json:"user_id"`
+	CreatedAt     time.Time `json:"created_at"`
+	PublicKeyPem  string    `json:"public_key_pem"`
+	PrivateKeyPem string    `json:"private_key_pem"`
+}
+
+type Block struct {
+	BlockerID uuid.UUID `json:"blocker_id"`
+	BlockedID uuid.UUID `json:"blocked_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Bookmark struct {
+	UserID       uuid.UUID     `json:"user_id"`
+	ChirpID      uuid.UUID     `json:"chirp_id"`
+	CollectionID uuid.NullUUID `json:"collection_id"`
+	CreatedAt    time.Time     `json:"created_at"`
+}
+
 type Chirp struct {
+	ID             uuid.UUID     `json:"id"`
+	CreatedAt      time.Time     `json:"created_at"`
+	UpdatedAt      time.Time     `json:"updated_at"`
+	Body           string        `json:"body"`
+	UserID         uuid.UUID     `json:"user_id"`
+	Visibility     string        `json:"visibility"`
+	ContentWarning string        `json:"content_warning"`
+	Search         string        `json:"search"`
+	DeletedAt      sql.NullTime  `json:"deleted_at"`
+	DeletedBy      uuid.NullUUID `json:"deleted_by"`
+}
+
+type ChirpEvent struct {
+	ID        int64         `json:"id"`
+	CreatedAt time.Time     `json:"created_at"`
+	Kind      string        `json:"kind"`
+	ChirpID   uuid.UUID     `json:"chirp_id"`
+	UserID    uuid.UUID     `json:"user_id"`
+	Seq       sql.NullInt64 `json:"seq"`
+}
+
+type ChirpHashtag struct {
+	ChirpID   uuid.UUID `json:"chirp_id"`
+	Tag       string    `json:"tag"`
+	ByteStart int32     `json:"byte_start"`
+	ByteEnd   int32     `json:"byte_end"`
+	CharStart int32     `json:"char_start"`
+	CharEnd   int32     `json:"char_end"`
+}
+
+type ChirpLink struct {
+	ChirpID   uuid.UUID `json:"chirp_id"`
+	Url       string    `json:"url"`
+	ByteStart int32     `json:"byte_start"`
+	ByteEnd   int32     `json:"byte_end"`
+	CharStart int32     `json:"char_start"`
+	CharEnd   int32     `json:"char_end"`
+}
+
+type ChirpMention struct {
+	ChirpID   uuid.UUID `json:"chirp_id"`
+	UserID    uuid.UUID `json:"user_id"`
+	Handle    string    `json:"handle"`
+	ByteStart int32     `json:"byte_start"`
+	ByteEnd   int32     `json:"byte_end"`
+	CharStart int32     `json:"char_start"`
+	CharEnd   int32     `json:"char_end"`
+}
+
+type Collection struct {
 	ID        uuid.UUID `json:"id"`
 	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	Body      string    `json:"body"`
 	UserID    uuid.UUID `json:"user_id"`
+	Name      string    `json:"name"`
+}
+
+type Conversation struct {
+	ID        uuid.UUID      `json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DirectKey sql.NullString `json:"direct_key"`
+}
+
+type ConversationMember struct {
+	ConversationID uuid.UUID `json:"conversation_id"`
+	UserID         uuid.UUID `json:"user_id"`
+	JoinedAt       time.Time `json:"joined_at"`
+	LastReadAt     time.Time `json:"last_read_at"`
+}
+
+type Delivery struct {
+	ID            uuid.UUID `json:"id"`
+	CreatedAt     time.Time `json:"created_at"`
+	UserID        uuid.UUID `json:"user_id"`
+	Inbox         string    `json:"inbox"`
+	Activity      []byte    `json:"activity"`
+	Attempts      int32     `json:"attempts"`
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+	LastError     string    `json:"last_error"`
+}
+
+type Draft struct {
+	ID             uuid.UUID    `json:"id"`
+	CreatedAt      time.Time    `json:"created_at"`
+	UpdatedAt      time.Time    `json:"updated_at"`
+	UserID         uuid.UUID    `json:"user_id"`
+	Body           string       `json:"body"`
+	Visibility     string       `json:"visibility"`
+	ContentWarning string       `json:"content_warning"`
+	PublishAt      sql.NullTime `json:"publish_at"`
+	LastError      string       `json:"last_error"`
+	Attempts       int32        `json:"attempts"`
+	RetryAt        sql.NullTime `json:"retry_at"`
+}
+
+type Follow struct {
+	FollowerID uuid.UUID `json:"follower_id"`
+	FolloweeID uuid.UUID `json:"followee_id"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type FollowRequest struct {
+	RequesterID uuid.UUID `json:"requester_id"`
+	TargetID    uuid.UUID `json:"target_id"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type HandleReservation struct {
+	Handle    string    `json:"handle"`
+	UserID    uuid.UUID `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type IdempotencyKey struct {
+	Scope       string          `json:"scope"`
+	Key         string          `json:"key"`
+	CreatedAt   time.Time       `json:"created_at"`
+	Fingerprint string          `json:"fingerprint"`
+	Status      sql.NullInt32   `json:"status"`
+	Headers     json.RawMessage `json:"headers"`
+	Body        []byte          `json:"body"`
+}
+
+type LinkPreview struct {
+	Url         string       `json:"url"`
+	CreatedAt   time.Time    `json:"created_at"`
+	FetchedAt   sql.NullTime `json:"fetched_at"`
+	Title       string       `json:"title"`
+	Description string       `json:"description"`
+	ImageUrl    string       `json:"image_url"`
+	Failed      bool         `json:"failed"`
+	ClaimedAt   sql.NullTime `json:"claimed_at"`
+}
+
+type List struct {
+	ID          uuid.UUID `json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	OwnerID     uuid.UUID `json:"owner_id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Private     bool      `json:"private"`
+}
+
+type ListMember struct {
+	ListID    uuid.UUID `json:"list_id"`
+	UserID    uuid.UUID `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Medium struct {
+	ID                   uuid.UUID     `json:"id"`
+	CreatedAt            time.Time     `json:"created_at"`
+	UserID               uuid.UUID     `json:"user_id"`
+	ChirpID              uuid.NullUUID `json:"chirp_id"`
+	Position             int32         `json:"position"`
+	ContentType          string        `json:"content_type"`
+	Width                int32         `json:"width"`
+	Height               int32         `json:"height"`
+	ThumbnailContentType string        `json:"thumbnail_content_type"`
+}
+
+type Message struct {
+	ID             uuid.UUID `json:"id"`
+	CreatedAt      time.Time `json:"created_at"`
+	ConversationID uuid.UUID `json:"conversation_id"`
+	SenderID       uuid.UUID `json:"sender_id"`
+	Body           string    `json:"body"`
+}
+
+type ModerationAction struct {
+	ID          uuid.UUID     `json:"id"`
+	CreatedAt   time.Time     `json:"created_at"`
+	ModeratorID uuid.NullUUID `json:"moderator_id"`
+	Action      string        `json:"action"`
+	ChirpID     uuid.UUID     `json:"chirp_id"`
+	AuthorID    uuid.UUID     `json:"author_id"`
+}
+
+type Moderator struct {
+	UserID    uuid.UUID `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Mute struct {
+	MuterID   uuid.UUID `json:"muter_id"`
+	MutedID   uuid.UUID `json:"muted_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Notification struct {
+	ID        uuid.UUID     `json:"id"`
+	CreatedAt time.Time     `json:"created_at"`
+	UserID    uuid.UUID     `json:"user_id"`
+	ActorID   uuid.UUID     `json:"actor_id"`
+	Kind      string        `json:"kind"`
+	ChirpID   uuid.NullUUID `json:"chirp_id"`
+	ReadAt    sql.NullTime  `json:"read_at"`
+}
+
+type PinnedChirp struct {
+	UserID    uuid.UUID `json:"user_id"`
+	ChirpID   uuid.UUID `json:"chirp_id"`
+	Position  int32     `json:"position"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Poll struct {
+	ChirpID  uuid.UUID `json:"chirp_id"`
+	Multiple bool      `json:"multiple"`
+	ClosesAt time.Time `json:"closes_at"`
+}
+
+type PollBallot struct {
+	ChirpID   uuid.UUID `json:"chirp_id"`
+	UserID    uuid.UUID `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type PollOption struct {
+	ChirpID  uuid.UUID `json:"chirp_id"`
+	Position int32     `json:"position"`
+	Title    string    `json:"title"`
+}
+
+type PollVote struct {
+	ChirpID  uuid.UUID `json:"chirp_id"`
+	UserID   uuid.UUID `json:"user_id"`
+	Position int32     `json:"position"`
 }
 
 type RefreshToken struct {
@@ -28,10 +274,62 @@ type RefreshToken struct {
 	RevokedAt sql.NullTime `json:"revoked_at"`
 }
 
+type RemoteActor struct {
+	ID           uuid.UUID `json:"id"`
+	Uri          string    `json:"uri"`
+	Handle       string    `json:"handle"`
+	Inbox        string    `json:"inbox"`
+	SharedInbox  string    `json:"shared_inbox"`
+	PublicKeyID  string    `json:"public_key_id"`
+	PublicKeyPem string    `json:"public_key_pem"`
+	FetchedAt    time.Time `json:"fetched_at"`
+}
+
+type RemoteFollow struct {
+	UserID    uuid.UUID `json:"user_id"`
+	ActorID   uuid.UUID `json:"actor_id"`
+	FollowUri string    `json:"follow_uri"`
+	Accepted  bool      `json:"accepted"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type RemoteFollower struct {
+	UserID    uuid.UUID `json:"user_id"`
+	ActorID   uuid.UUID `json:"actor_id"`
+	FollowUri string    `json:"follow_uri"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type TrendSnapshot struct {
+	ID          uuid.UUID `json:"id"`
+	WindowName  string    `json:"window_name"`
+	GeneratedAt time.Time `json:"generated_at"`
+}
+
+type TrendingChirp struct {
+	SnapshotID uuid.UUID `json:"snapshot_id"`
+	Position   int32     `json:"position"`
+	ChirpID    uuid.UUID `json:"chirp_id"`
+	Score      float64   `json:"score"`
+}
+
+type TrendingHashtag struct {
+	SnapshotID uuid.UUID `json:"snapshot_id"`
+	Position   int32     `json:"position"`
+	Tag        string    `json:"tag"`
+	Uses       int64     `json:"uses"`
+	Score      float64   `json:"score"`
+}
+
 type User struct {
 	ID             uuid.UUID `json:"id"`
 	CreatedAt      time.Time `json:"created_at"`
 	UpdatedAt      time.Time `json:"updated_at"`
 	Email          string    `json:"email"`
 	HashedPassword string    `json:"hashed_password"`
+	Handle         string    `json:"handle"`
+	DisplayName    string    `json:"display_name"`
+	Bio            string    `json:"bio"`
+	AvatarUrl      string    `json:"avatar_url"`
+	Private        bool      `json:"private"`
 }
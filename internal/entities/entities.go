@@ -0,0 +1,262 @@
+package entities
+
+import (
+	"net/url"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MaxHandleLength is the longest handle a user can register or mention.
+const MaxHandleLength = 15
+
+// MaxHashtagLength is the longest hashtag, in characters, that is picked
+// out of a chirp. Longer runs are left as plain text.
+const MaxHashtagLength = 50
+
+// MaxURLLength is the longest link that is picked out of a chirp.
+const MaxURLLength = 2048
+
+// reservedHandles can't be registered by anyone, either because they would
+// shadow a route like /api/users/me or could pass for an official account.
+var reservedHandles = map[string]bool{
+	"admin":         true,
+	"administrator": true,
+	"api":           true,
+	"app":           true,
+	"chirpy":        true,
+	"help":          true,
+	"me":            true,
+	"moderator":     true,
+	"root":          true,
+	"security":      true,
+	"settings":      true,
+	"staff":         true,
+	"support":       true,
+	"system":        true,
+}
+
+// Mention is an @handle token found in a chirp body. Start and End are byte
+// offsets into the body; CharStart and CharEnd count Unicode code points.
+// Both ranges include the leading '@'.
+type Mention struct {
+	Handle    string
+	Start     int
+	End       int
+	CharStart int
+	CharEnd   int
+}
+
+// Hashtag is a #tag token found in a chirp body. Tag is normalized and
+// excludes the '#'; the offsets work like Mention's.
+type Hashtag struct {
+	Tag       string
+	Start     int
+	End       int
+	CharStart int
+	CharEnd   int
+}
+
+// Link is an http or https URL found in a chirp body, with trailing
+// punctuation left off. The offsets work like Mention's.
+type Link struct {
+	URL       string
+	Start     int
+	End       int
+	CharStart int
+	CharEnd   int
+}
+
+func ValidHandle(handle string) bool {
+	if len(handle) == 0 || len(handle) > MaxHandleLength {
+		return false
+	}
+	for i := 0; i < len(handle); i++ {
+		if !isHandleByte(handle[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// NormalizeHandle returns the form handles are compared in. Handles keep the
+// casing the user chose, but are unique regardless of case.
+func NormalizeHandle(handle string) string {
+	return strings.ToLower(handle)
+}
+
+func IsReservedHandle(handle string) bool {
+	return reservedHandles[NormalizeHandle(handle)]
+}
+
+func ParseMentions(body string) []Mention {
+	mentions := []Mention{}
+	chars := 0
+	prev := ' '
+	for i := 0; i < len(body); {
+		r, size := utf8.DecodeRuneInString(body[i:])
+		if end := matchURL(body, i, prev); end > 0 {
+			chars += utf8.RuneCountInString(body[i:end])
+			prev, _ = utf8.DecodeLastRuneInString(body[:end])
+			i = end
+			continue
+		}
+		if r == '@' && !isWordRune(prev) && prev != '@' {
+			end := i + 1
+			for end < len(body) && isHandleByte(body[end]) {
+				end++
+			}
+			handle := body[i+1 : end]
+			followedByAt := end < len(body) && body[end] == '@'
+			if ValidHandle(handle) && !followedByAt {
+				mentions = append(mentions, Mention{
+					Handle:    handle,
+					Start:     i,
+					End:       end,
+					CharStart: chars,
+					CharEnd:   chars + end - i,
+				})
+				chars += end - i
+				prev = rune(body[end-1])
+				i = end
+				continue
+			}
+		}
+		prev = r
+		chars++
+		i += size
+	}
+	return mentions
+}
+
+// ParseHashtags finds #tags made of letters, digits and underscores. A tag
+// needs at least one letter, so "#1" stays a number.
+func ParseHashtags(body string) []Hashtag {
+	hashtags := []Hashtag{}
+	chars := 0
+	prev := ' '
+	for i := 0; i < len(body); {
+		r, size := utf8.DecodeRuneInString(body[i:])
+		if end := matchURL(body, i, prev); end > 0 {
+			chars += utf8.RuneCountInString(body[i:end])
+			prev, _ = utf8.DecodeLastRuneInString(body[:end])
+			i = end
+			continue
+		}
+		if r == '#' && !isWordRune(prev) && prev != '#' && prev != '&' {
+			end := i + size
+			tagChars := 0
+			hasLetter := false
+			for end < len(body) {
+				tr, tsize := utf8.DecodeRuneInString(body[end:])
+				if !isWordRune(tr) {
+					break
+				}
+				hasLetter = hasLetter || unicode.IsLetter(tr)
+				tagChars++
+				end += tsize
+			}
+			if hasLetter && tagChars <= MaxHashtagLength {
+				hashtags = append(hashtags, Hashtag{
+					Tag:       NormalizeHashtag(body[i+size : end]),
+					Start:     i,
+					End:       end,
+					CharStart: chars,
+					CharEnd:   chars + 1 + tagChars,
+				})
+				chars += 1 + tagChars
+				prev, _ = utf8.DecodeLastRuneInString(body[:end])
+				i = end
+				continue
+			}
+		}
+		prev = r
+		chars++
+		i += size
+	}
+	return hashtags
+}
+
+func NormalizeHashtag(tag string) string {
+	return strings.ToLower(tag)
+}
+
+// ParseLinks finds http and https URLs. Mentions and hashtags inside a link,
+// such as a #fragment, belong to the link and aren't parsed separately.
+func ParseLinks(body string) []Link {
+	links := []Link{}
+	chars := 0
+	prev := ' '
+	for i := 0; i < len(body); {
+		if end := matchURL(body, i, prev); end > 0 {
+			n := utf8.RuneCountInString(body[i:end])
+			links = append(links, Link{
+				URL:       body[i:end],
+				Start:     i,
+				End:       end,
+				CharStart: chars,
+				CharEnd:   chars + n,
+			})
+			chars += n
+			prev, _ = utf8.DecodeLastRuneInString(body[:end])
+			i = end
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(body[i:])
+		prev = r
+		chars++
+		i += size
+	}
+	return links
+}
+
+// matchURL reports where the link starting at body[i] ends, or 0 if there
+// isn't one. Trailing punctuation and a closing bracket with no opening one
+// in the link are taken to be part of the sentence around it.
+func matchURL(body string, i int, prev rune) int {
+	if isWordRune(prev) {
+		return 0
+	}
+	rest := strings.ToLower(body[i:min(len(body), i+len("https://"))])
+	if !strings.HasPrefix(rest, "http://") && !strings.HasPrefix(rest, "https://") {
+		return 0
+	}
+
+	end := i
+	for end < len(body) {
+		r, size := utf8.DecodeRuneInString(body[end:])
+		if unicode.IsSpace(r) || unicode.IsControl(r) || strings.ContainsRune(`<>"`, r) {
+			break
+		}
+		end += size
+	}
+	for end > i {
+		r, size := utf8.DecodeLastRuneInString(body[i:end])
+		if strings.ContainsRune(".,:;!?'", r) {
+			end -= size
+			continue
+		}
+		if r == ')' && strings.Count(body[i:end], "(") < strings.Count(body[i:end], ")") {
+			end -= size
+			continue
+		}
+		break
+	}
+
+	if end-i > MaxURLLength {
+		return 0
+	}
+	u, err := url.Parse(body[i:end])
+	if err != nil || u.Hostname() == "" {
+		return 0
+	}
+	return end
+}
+
+func isHandleByte(b byte) bool {
+	return b == '_' || (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z') || (b >= '0' && b <= '9')
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,112 @@
+package entities
+
+import (
+	"testing"
+)
+
+func TestParseMentions(t *testing.T) {
+	body := "héllo @alice and @Bob_2, not me@example.com or @this_handle_is_too_long"
+	mentions := ParseMentions(body)
+	if len(mentions) != 2 {
+		t.Fatalf("expected 2 mentions, got %d: %+v", len(mentions), mentions)
+	}
+
+	alice := mentions[0]
+	if alice.Handle != "alice" || body[alice.Start:alice.End] != "@alice" {
+		t.Errorf("unexpected mention %+v", alice)
+	}
+	if alice.CharStart != 6 || alice.CharEnd != 12 {
+		t.Errorf("expected char offsets 6-12, got %d-%d", alice.CharStart, alice.CharEnd)
+	}
+
+	bob := mentions[1]
+	if bob.Handle != "Bob_2" || body[bob.Start:bob.End] != "@Bob_2" {
+		t.Errorf("unexpected mention %+v", bob)
+	}
+	if bob.End-bob.Start != bob.CharEnd-bob.CharStart {
+		t.Errorf("byte and char lengths differ for %+v", bob)
+	}
+}
+
+func TestValidHandle(t *testing.T) {
+	valid := []string{"a", "alice", "Bob_2", "fifteen_chars__"}
+	invalid := []string{"", "sixteen_chars___", "with space", "dash-es", "ünicode"}
+	for _, h := range valid {
+		if !ValidHandle(h) {
+			t.Errorf("expected %q to be valid", h)
+		}
+	}
+	for _, h := range invalid {
+		if ValidHandle(h) {
+			t.Errorf("expected %q to be invalid", h)
+		}
+	}
+}
+
+func TestIsReservedHandle(t *testing.T) {
+	if !IsReservedHandle("Admin") {
+		t.Errorf("expected reserved handles to match regardless of case")
+	}
+	if IsReservedHandle("alice") {
+		t.Errorf("expected alice not to be reserved")
+	}
+}
+
+func TestParseHashtags(t *testing.T) {
+	body := "Été #Golang, #café_2 #1 a#b &#39; ##double"
+	hashtags := ParseHashtags(body)
+	if len(hashtags) != 2 {
+		t.Fatalf("expected 2 hashtags, got %d: %+v", len(hashtags), hashtags)
+	}
+
+	golang := hashtags[0]
+	if golang.Tag != "golang" || body[golang.Start:golang.End] != "#Golang" {
+		t.Errorf("unexpected hashtag %+v", golang)
+	}
+	if golang.CharStart != 4 || golang.CharEnd != 11 {
+		t.Errorf("expected char offsets 4-11, got %d-%d", golang.CharStart, golang.CharEnd)
+	}
+
+	cafe := hashtags[1]
+	if cafe.Tag != "café_2" || body[cafe.Start:cafe.End] != "#café_2" {
+		t.Errorf("unexpected hashtag %+v", cafe)
+	}
+	if cafe.CharEnd-cafe.CharStart != 7 {
+		t.Errorf("expected 7 characters, got %d", cafe.CharEnd-cafe.CharStart)
+	}
+}
+
+func TestParseLinks(t *testing.T) {
+	body := "Née ici: https://example.com/a_(b)?q=1#frag. (see http://Example.org/x) ftp://no https:// nohttps://x.y"
+	links := ParseLinks(body)
+	if len(links) != 2 {
+		t.Fatalf("expected 2 links, got %d: %+v", len(links), links)
+	}
+
+	first := links[0]
+	if first.URL != "https://example.com/a_(b)?q=1#frag" || body[first.Start:first.End] != first.URL {
+		t.Errorf("unexpected link %+v", first)
+	}
+	if first.CharStart != 9 || first.CharEnd != 9+len(first.URL) {
+		t.Errorf("expected char offsets 9-%d, got %d-%d", 9+len(first.URL), first.CharStart, first.CharEnd)
+	}
+
+	if links[1].URL != "http://Example.org/x" {
+		t.Errorf("expected the closing bracket to be left off, got %q", links[1].URL)
+	}
+}
+
+func TestEntitiesInsideLinks(t *testing.T) {
+	body := "#go https://example.com/#anchor/@alice @bob"
+	hashtags := ParseHashtags(body)
+	if len(hashtags) != 1 || hashtags[0].Tag != "go" {
+		t.Errorf("expected only #go, got %+v", hashtags)
+	}
+	mentions := ParseMentions(body)
+	if len(mentions) != 1 || mentions[0].Handle != "bob" {
+		t.Errorf("expected only @bob, got %+v", mentions)
+	}
+	if mentions[0].CharStart != len([]rune(body))-4 {
+		t.Errorf("expected @bob to start at %d, got %d", len([]rune(body))-4, mentions[0].CharStart)
+	}
+}
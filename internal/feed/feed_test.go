@@ -0,0 +1,93 @@
+package feed
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testFeed() Feed {
+	published := time.Date(2025, 3, 1, 12, 0, 0, 0, time.FixedZone("CET", 3600))
+	return Feed{
+		ID:      "https://chirpy.example/users/alice/feed.atom",
+		Title:   "@alice on Chirpy",
+		Link:    "https://chirpy.example/api/users/alice",
+		Self:    "https://chirpy.example/users/alice/feed.atom",
+		Updated: published,
+		Entries: []Entry{{
+			ID:         "urn:uuid:0b6d5e1c-2f7a-4f3e-9d2a-6a1c6c2f0e11",
+			Title:      `<b>"Tom & Jerry"</b>`,
+			Link:       "https://chirpy.example/api/chirps/0b6d5e1c-2f7a-4f3e-9d2a-6a1c6c2f0e11?a=1&b=2",
+			Content:    "a < b && c > d\x00",
+			Author:     "alice",
+			Published:  published,
+			Updated:    published,
+			Categories: []string{"go"},
+		}},
+	}
+}
+
+func TestAtom(t *testing.T) {
+	body, err := testFeed().Atom()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var doc atomFeed
+	if err := xml.Unmarshal(body, &doc); err != nil {
+		t.Fatalf("feed isn't well-formed: %v\n%s", err, body)
+	}
+	if len(doc.Entries) != 1 {
+		t.Fatalf("expected 1 entry, got %d", len(doc.Entries))
+	}
+	entry := doc.Entries[0]
+	if entry.Title != `<b>"Tom & Jerry"</b>` {
+		t.Errorf("expected the title to round-trip, got %q", entry.Title)
+	}
+	if entry.Content.Body != "a < b && c > d�" {
+		t.Errorf("expected content to round-trip with the NUL replaced, got %q", entry.Content.Body)
+	}
+	if entry.ID != "urn:uuid:0b6d5e1c-2f7a-4f3e-9d2a-6a1c6c2f0e11" {
+		t.Errorf("unexpected id %q", entry.ID)
+	}
+	if entry.Published != "2025-03-01T11:00:00Z" {
+		t.Errorf("expected published in UTC, got %q", entry.Published)
+	}
+	if entry.Links[0].Href != testFeed().Entries[0].Link {
+		t.Errorf("expected the link to round-trip, got %q", entry.Links[0].Href)
+	}
+}
+
+func TestRSS(t *testing.T) {
+	body, err := testFeed().RSS()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(body), xml.Header) {
+		t.Errorf("expected an XML declaration")
+	}
+	if !strings.Contains(string(body), `<guid isPermaLink="false">urn:uuid:0b6d5e1c-2f7a-4f3e-9d2a-6a1c6c2f0e11</guid>`) {
+		t.Errorf("expected a guid that isn't a permalink:\n%s", body)
+	}
+
+	var doc struct {
+		Channel struct {
+			Items []struct {
+				Title       string `xml:"title"`
+				Description string `xml:"description"`
+				PubDate     string `xml:"pubDate"`
+			} `xml:"item"`
+		} `xml:"channel"`
+	}
+	if err := xml.Unmarshal(body, &doc); err != nil {
+		t.Fatalf("feed isn't well-formed: %v\n%s", err, body)
+	}
+	item := doc.Channel.Items[0]
+	if item.Title != `<b>"Tom & Jerry"</b>` || item.Description != "a < b && c > d�" {
+		t.Errorf("expected text to round-trip, got %q and %q", item.Title, item.Description)
+	}
+	if item.PubDate != "Sat, 01 Mar 2025 11:00:00 +0000" {
+		t.Errorf("unexpected pubDate %q", item.PubDate)
+	}
+}
@@ -0,0 +1,87 @@
+package httpcache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ETag returns a strong entity tag for a response body. Two bodies get the
+// same tag only if they are byte for byte the same.
+func ETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// NotModified reports whether a GET or HEAD request's preconditions show
+// that the client's copy, with tag etag and last changed at lastModified,
+// is current. As RFC 9110 asks, If-Modified-Since is only looked at when
+// there is no If-None-Match. A zero lastModified never matches.
+func NotModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if r.Method != "GET" && r.Method != "HEAD" {
+		return false
+	}
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		return matchAny(inm, etag, true)
+	}
+	ims := r.Header.Get("If-Modified-Since")
+	if ims == "" || lastModified.IsZero() {
+		return false
+	}
+	t, err := http.ParseTime(ims)
+	if err != nil {
+		return false
+	}
+	return !lastModified.Truncate(time.Second).After(t)
+}
+
+// matchAny reports whether a list of entity tags from a precondition
+// header contains etag. The weak comparison ignores W/ prefixes.
+func matchAny(header, etag string, weak bool) bool {
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+	if weak {
+		etag = strings.TrimPrefix(etag, "W/")
+	}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if weak {
+			tag = strings.TrimPrefix(tag, "W/")
+		}
+		if tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// Write sends body with its validators, or an empty 304 when NotModified
+// says the client already has it. Responses are marked as varying with
+// Authorization, since what a viewer sees depends on who they are.
+func Write(w http.ResponseWriter, r *http.Request, contentType string, body []byte, lastModified time.Time) {
+	WriteTag(w, r, contentType, body, ETag(body), lastModified)
+}
+
+// WriteTag is Write with the entity tag given rather than taken from the
+// body. A handler whose tag comes from a cheap query can check NotModified
+// with it and skip building the body; body is only read when NotModified
+// doesn't hold.
+func WriteTag(w http.ResponseWriter, r *http.Request, contentType string, body []byte, etag string, lastModified time.Time) {
+	w.Header().Set("ETag", etag)
+	if !lastModified.IsZero() {
+		w.Header().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+	w.Header().Add("Vary", "Authorization")
+	if NotModified(r, etag, lastModified) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	if r.Method != "HEAD" {
+		w.Write(body)
+	}
+}
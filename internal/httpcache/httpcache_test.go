@@ -0,0 +1,98 @@
+package httpcache
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNotModified(t *testing.T) {
+	modified := time.Date(2025, 3, 1, 12, 0, 0, 500, time.UTC)
+	etag := ETag([]byte(`{"id":1}`))
+
+	cases := []struct {
+		name    string
+		method  string
+		headers map[string]string
+		want    bool
+	}{
+		{"no preconditions", "GET", nil, false},
+		{"matching tag", "GET", map[string]string{"If-None-Match": `"other", ` + etag}, true},
+		{"weak form of tag", "GET", map[string]string{"If-None-Match": "W/" + etag}, true},
+		{"stale tag", "GET", map[string]string{"If-None-Match": `"other"`}, false},
+		{"any tag", "GET", map[string]string{"If-None-Match": "*"}, true},
+		{"not modified since", "GET", map[string]string{"If-Modified-Since": modified.Format(http.TimeFormat)}, true},
+		{"modified since", "GET", map[string]string{"If-Modified-Since": modified.Add(-time.Second).Format(http.TimeFormat)}, false},
+		{"tag wins over date", "GET", map[string]string{
+			"If-None-Match":     `"other"`,
+			"If-Modified-Since": modified.Format(http.TimeFormat),
+		}, false},
+		{"bad date", "GET", map[string]string{"If-Modified-Since": "yesterday"}, false},
+		{"not a read", "POST", map[string]string{"If-None-Match": etag}, false},
+	}
+	for _, c := range cases {
+		r := httptest.NewRequest(c.method, "/api/chirps", nil)
+		for k, v := range c.headers {
+			r.Header.Set(k, v)
+		}
+		if got := NotModified(r, etag, modified); got != c.want {
+			t.Errorf("%s: expected %v, got %v", c.name, c.want, got)
+		}
+	}
+}
+
+func TestWrite(t *testing.T) {
+	body := []byte(`[{"id":1}]`)
+	modified := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	w := httptest.NewRecorder()
+	Write(w, httptest.NewRequest("GET", "/api/chirps", nil), "application/json", body, modified)
+	if w.Code != 200 || w.Body.String() != string(body) {
+		t.Fatalf("expected the body with 200, got %d %q", w.Code, w.Body.String())
+	}
+	etag := w.Header().Get("ETag")
+	if etag != ETag(body) {
+		t.Errorf("expected ETag %s, got %s", ETag(body), etag)
+	}
+	if w.Header().Get("Last-Modified") != "Sat, 01 Mar 2025 12:00:00 GMT" {
+		t.Errorf("unexpected Last-Modified %q", w.Header().Get("Last-Modified"))
+	}
+
+	r := httptest.NewRequest("GET", "/api/chirps", nil)
+	r.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	Write(w, r, "application/json", body, modified)
+	if w.Code != 304 || w.Body.Len() != 0 {
+		t.Errorf("expected an empty 304, got %d %q", w.Code, w.Body.String())
+	}
+	if w.Header().Get("ETag") != etag {
+		t.Errorf("expected the 304 to repeat the ETag")
+	}
+
+	if ETag([]byte(`[{"id":2}]`)) == etag {
+		t.Errorf("expected different bodies to get different tags")
+	}
+}
+
+func TestWriteTag(t *testing.T) {
+	etag := ETag([]byte("feed v1"))
+
+	r := httptest.NewRequest("GET", "/api/users/alice/feed.atom", nil)
+	r.Header.Set("If-None-Match", etag)
+	w := httptest.NewRecorder()
+	WriteTag(w, r, "application/atom+xml", nil, etag, time.Time{})
+	if w.Code != 304 || w.Header().Get("ETag") != etag {
+		t.Fatalf("expected a 304 with the given tag, got %d %q", w.Code, w.Header().Get("ETag"))
+	}
+	if w.Header().Get("Last-Modified") != "" {
+		t.Errorf("expected no Last-Modified for a zero time")
+	}
+
+	r.Header.Set("If-None-Match", ETag([]byte("feed v0")))
+	w = httptest.NewRecorder()
+	WriteTag(w, r, "application/atom+xml", []byte("<feed/>"), etag, time.Time{})
+	if w.Code != 200 || w.Body.String() != "<feed/>" || w.Header().Get("ETag") != etag {
+		t.Errorf("expected the body with the given tag, got %d %q %q", w.Code, w.Body.String(), w.Header().Get("ETag"))
+	}
+}
@@ -0,0 +1,194 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+
+	"golang.org/x/image/draw"
+)
+
+const (
+	// MaxPixels guards against images that are small files but would take
+	// far too much memory to decode. For a GIF it caps every frame's
+	// pixels added together.
+	MaxPixels = 40_000_000
+	// MaxFrames is the most frames a GIF may have.
+	MaxFrames = 500
+	// ThumbnailSize is the longest side of a thumbnail, in pixels.
+	ThumbnailSize = 400
+)
+
+var (
+	ErrUnsupported = errors.New("only JPEG, PNG and GIF images are supported")
+	ErrTooLarge    = errors.New("image dimensions are too large")
+	errBadGIF      = errors.New("gif: malformed file")
+)
+
+type Image struct {
+	Data        []byte
+	ContentType string
+	Width       int
+	Height      int
+}
+
+// Process sniffs data's type from its contents, then decodes it and encodes
+// it again along with a thumbnail. Encoding from the pixels leaves behind
+// everything else the file carried, EXIF included. That takes any EXIF
+// orientation with it, so photos show the way the camera stored them.
+func Process(data []byte) (Image, Image, error) {
+	contentType := http.DetectContentType(data)
+	switch contentType {
+	case "image/jpeg", "image/png", "image/gif":
+	default:
+		return Image{}, Image{}, ErrUnsupported
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return Image{}, Image{}, err
+	}
+	if config.Width*config.Height > MaxPixels {
+		return Image{}, Image{}, ErrTooLarge
+	}
+	if contentType == "image/gif" {
+		err = checkFrames(data)
+		if err != nil {
+			return Image{}, Image{}, err
+		}
+	}
+
+	var img image.Image
+	var out bytes.Buffer
+	switch contentType {
+	case "image/jpeg":
+		img, err = jpeg.Decode(bytes.NewReader(data))
+		if err == nil {
+			err = jpeg.Encode(&out, img, &jpeg.Options{Quality: 90})
+		}
+	case "image/png":
+		img, err = png.Decode(bytes.NewReader(data))
+		if err == nil {
+			err = png.Encode(&out, img)
+		}
+	case "image/gif":
+		// Keep every frame so animations still play.
+		var g *gif.GIF
+		g, err = gif.DecodeAll(bytes.NewReader(data))
+		if err == nil {
+			img = g.Image[0]
+			err = gif.EncodeAll(&out, g)
+		}
+	}
+	if err != nil {
+		return Image{}, Image{}, err
+	}
+
+	full := Image{
+		Data:        out.Bytes(),
+		ContentType: contentType,
+		Width:       config.Width,
+		Height:      config.Height,
+	}
+	thumb, err := thumbnail(img, contentType == "image/jpeg")
+	if err != nil {
+		return Image{}, Image{}, err
+	}
+	return full, thumb, nil
+}
+
+// checkFrames walks a GIF's blocks without decompressing anything and
+// fails with ErrTooLarge if decoding every frame would go over MaxFrames
+// or MaxPixels. DecodeConfig only reports the logical screen, and each
+// frame is decoded into its own image.
+func checkFrames(data []byte) error {
+	if len(data) < 13 {
+		return errBadGIF
+	}
+	i := 13
+	if data[10]&0x80 != 0 {
+		i += 3 << (data[10]&0x07 + 1)
+	}
+
+	// skipSubBlocks steps over a run of length-prefixed data sub-blocks.
+	skipSubBlocks := func() bool {
+		for i < len(data) {
+			n := int(data[i])
+			i += 1 + n
+			if n == 0 {
+				return i <= len(data)
+			}
+		}
+		return false
+	}
+
+	frames, pixels := 0, 0
+	for i < len(data) {
+		switch data[i] {
+		case 0x21: // extension: label, then sub-blocks
+			i += 2
+			if !skipSubBlocks() {
+				return errBadGIF
+			}
+		case 0x2C: // image descriptor
+			if i+10 > len(data) {
+				return errBadGIF
+			}
+			width := int(data[i+5]) | int(data[i+6])<<8
+			height := int(data[i+7]) | int(data[i+8])<<8
+			flags := data[i+9]
+			i += 10
+			if flags&0x80 != 0 {
+				i += 3 << (flags&0x07 + 1)
+			}
+			frames++
+			pixels += width * height
+			if frames > MaxFrames || pixels > MaxPixels {
+				return ErrTooLarge
+			}
+			i++ // LZW minimum code size
+			if !skipSubBlocks() {
+				return errBadGIF
+			}
+		case 0x3B: // trailer
+			return nil
+		default:
+			return errBadGIF
+		}
+	}
+	return nil
+}
+
+// thumbnail scales img down to fit ThumbnailSize. Photos become JPEGs;
+// anything that might have transparency stays PNG.
+func thumbnail(img image.Image, photo bool) (Image, error) {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width > ThumbnailSize || height > ThumbnailSize {
+		if width >= height {
+			width, height = ThumbnailSize, max(1, height*ThumbnailSize/width)
+		} else {
+			width, height = max(1, width*ThumbnailSize/height), ThumbnailSize
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, draw.Over, nil)
+
+	var out bytes.Buffer
+	thumb := Image{Width: width, Height: height}
+	var err error
+	if photo {
+		thumb.ContentType = "image/jpeg"
+		err = jpeg.Encode(&out, dst, &jpeg.Options{Quality: 85})
+	} else {
+		thumb.ContentType = "image/png"
+		err = png.Encode(&out, dst)
+	}
+	thumb.Data = out.Bytes()
+	return thumb, err
+}
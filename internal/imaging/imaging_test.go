@@ -0,0 +1,84 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/gif"
+	"image/jpeg"
+	"testing"
+)
+
+func TestProcessStripsEXIF(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 800, 600))
+	for x := 0; x < 800; x++ {
+		img.Set(x, x%600, color.RGBA{R: 255, A: 255})
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// Splice an APP1 EXIF segment in right after the start-of-image marker.
+	payload := append([]byte("Exif\x00\x00"), []byte("GPS 51.5N 0.1W")...)
+	segment := []byte{0xFF, 0xE1, byte((len(payload) + 2) >> 8), byte(len(payload) + 2)}
+	segment = append(segment, payload...)
+	data := append([]byte{}, buf.Bytes()[:2]...)
+	data = append(data, segment...)
+	data = append(data, buf.Bytes()[2:]...)
+
+	full, thumb, err := Process(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(full.Data, []byte("Exif")) || bytes.Contains(full.Data, []byte("GPS")) {
+		t.Errorf("expected EXIF to be stripped")
+	}
+	if full.ContentType != "image/jpeg" || full.Width != 800 || full.Height != 600 {
+		t.Errorf("unexpected image %s %dx%d", full.ContentType, full.Width, full.Height)
+	}
+	if thumb.Width != 400 || thumb.Height != 300 {
+		t.Errorf("expected a 400x300 thumbnail, got %dx%d", thumb.Width, thumb.Height)
+	}
+	if _, err := jpeg.Decode(bytes.NewReader(thumb.Data)); err != nil {
+		t.Errorf("thumbnail doesn't decode: %v", err)
+	}
+}
+
+func TestProcessRejectsOtherTypes(t *testing.T) {
+	_, _, err := Process([]byte("<svg xmlns=\"http://www.w3.org/2000/svg\"></svg>"))
+	if !errors.Is(err, ErrUnsupported) {
+		t.Errorf("expected ErrUnsupported, got %v", err)
+	}
+}
+
+func TestProcessLimitsGIFFrames(t *testing.T) {
+	animation := func(frames int) []byte {
+		g := &gif.GIF{}
+		for i := 0; i < frames; i++ {
+			g.Image = append(g.Image, image.NewPaletted(image.Rect(0, 0, 4, 4), palette.Plan9))
+			g.Delay = append(g.Delay, 10)
+		}
+		var buf bytes.Buffer
+		if err := gif.EncodeAll(&buf, g); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+
+	full, _, err := Process(animation(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := gif.DecodeAll(bytes.NewReader(full.Data))
+	if err != nil || len(g.Image) != 3 {
+		t.Errorf("expected the animation to keep 3 frames, got %v", err)
+	}
+
+	_, _, err = Process(animation(MaxFrames + 1))
+	if !errors.Is(err, ErrTooLarge) {
+		t.Errorf("expected ErrTooLarge for %d frames, got %v", MaxFrames+1, err)
+	}
+}
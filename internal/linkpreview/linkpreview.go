@@ -0,0 +1,265 @@
+package linkpreview
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	maxRedirects         = 3
+	maxTitleLength       = 200
+	maxDescriptionLength = 300
+)
+
+// ErrBlocked is returned when a link resolves to an address the fetcher
+// won't connect to, such as a loopback or private one.
+var ErrBlocked = errors.New("address is not publicly routable")
+
+// ErrNoPreview is returned for pages that don't have a title.
+var ErrNoPreview = errors.New("page has no preview")
+
+// blockedPrefixes are the special-purpose ranges that netip.Addr's own
+// checks don't cover.
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("192.0.2.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("198.51.100.0/24"),
+	netip.MustParsePrefix("203.0.113.0/24"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("64:ff9b:1::/48"),
+	netip.MustParsePrefix("2001:db8::/32"),
+	netip.MustParsePrefix("2002::/16"),
+}
+
+var (
+	headEndRe = regexp.MustCompile(`(?i)</head\s*>`)
+	titleRe   = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title\s*>`)
+	metaRe    = regexp.MustCompile(`(?is)<meta\s[^>]*>`)
+	attrRe    = regexp.MustCompile(`(?is)([a-z:_-]+)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+)
+
+// Doer sends HTTP requests. *http.Client is one; NewClient returns one that
+// only connects to public addresses.
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Preview is what a link card shows. ImageURL is absolute, or empty when
+// the page doesn't name an image.
+type Preview struct {
+	Title       string
+	Description string
+	ImageURL    string
+}
+
+// Fetcher reads link previews from pages' titles and Open Graph tags.
+type Fetcher struct {
+	client   Doer
+	timeout  time.Duration
+	maxBytes int64
+}
+
+// New returns a Fetcher that gives each page timeout to answer and reads at
+// most maxBytes of it. The client decides which addresses may be reached.
+func New(client Doer, timeout time.Duration, maxBytes int64) *Fetcher {
+	return &Fetcher{
+		client:   client,
+		timeout:  timeout,
+		maxBytes: maxBytes,
+	}
+}
+
+// NewClient returns an HTTP client for fetching untrusted links. It checks
+// every address it connects to, after DNS resolution and on each redirect,
+// so a hostname can't point it at the internal network. It ignores proxy
+// settings, which would hide the real address.
+func NewClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, c syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			addr, err := netip.ParseAddr(host)
+			if err != nil {
+				return err
+			}
+			if !Allowed(addr) {
+				return fmt.Errorf("%s: %w", addr, ErrBlocked)
+			}
+			return nil
+		},
+	}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			Proxy:                  nil,
+			DialContext:            dialer.DialContext,
+			TLSHandshakeTimeout:    timeout,
+			ResponseHeaderTimeout:  timeout,
+			MaxResponseHeaderBytes: 16 << 10,
+			DisableKeepAlives:      true,
+		},
+		CheckRedirect: checkRedirect,
+	}
+}
+
+// Allowed reports whether addr is a public unicast address.
+func Allowed(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+	for _, p := range blockedPrefixes {
+		if p.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+func checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxRedirects)
+	}
+	return checkScheme(req.URL)
+}
+
+func checkScheme(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return errors.New("link has no host")
+	}
+	return nil
+}
+
+// Fetch reads the preview for the page at link. Anything past the fetcher's
+// size limit is ignored, so tags late in a huge page aren't found.
+func (f *Fetcher) Fetch(ctx context.Context, link string) (Preview, error) {
+	u, err := url.Parse(link)
+	if err != nil {
+		return Preview{}, err
+	}
+	if err := checkScheme(u); err != nil {
+		return Preview{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, f.timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+	if err != nil {
+		return Preview{}, err
+	}
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+	req.Header.Set("User-Agent", "chirpy-linkpreview/1.0")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return Preview{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return Preview{}, fmt.Errorf("page returned %s", resp.Status)
+	}
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil || (mediaType != "text/html" && mediaType != "application/xhtml+xml") {
+		return Preview{}, fmt.Errorf("page is not HTML")
+	}
+
+	page, err := io.ReadAll(io.LimitReader(resp.Body, f.maxBytes))
+	if err != nil {
+		return Preview{}, err
+	}
+
+	base := u
+	if resp.Request != nil && resp.Request.URL != nil {
+		base = resp.Request.URL
+	}
+	preview := parse(string(page), base)
+	if preview.Title == "" {
+		return Preview{}, ErrNoPreview
+	}
+	return preview, nil
+}
+
+// parse picks the preview out of a page's head, preferring Open Graph tags,
+// then Twitter card tags, then the plain title and description.
+func parse(page string, base *url.URL) Preview {
+	page = strings.ToValidUTF8(page, "�")
+	if loc := headEndRe.FindStringIndex(page); loc != nil {
+		page = page[:loc[0]]
+	}
+
+	meta := map[string]string{}
+	for _, tag := range metaRe.FindAllString(page, -1) {
+		attrs := map[string]string{}
+		for _, m := range attrRe.FindAllStringSubmatch(tag, -1) {
+			attrs[strings.ToLower(m[1])] = m[2] + m[3] + m[4]
+		}
+		key := attrs["property"]
+		if key == "" {
+			key = attrs["name"]
+		}
+		key = strings.ToLower(key)
+		if _, seen := meta[key]; key != "" && !seen {
+			meta[key] = clean(attrs["content"])
+		}
+	}
+
+	title := ""
+	if m := titleRe.FindStringSubmatch(page); m != nil {
+		title = clean(m[1])
+	}
+
+	preview := Preview{
+		Title:       truncate(first(meta["og:title"], meta["twitter:title"], title), maxTitleLength),
+		Description: truncate(first(meta["og:description"], meta["twitter:description"], meta["description"]), maxDescriptionLength),
+	}
+	if image := first(meta["og:image"], meta["og:image:url"], meta["twitter:image"]); image != "" {
+		if u, err := base.Parse(image); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+			preview.ImageURL = u.String()
+		}
+	}
+	return preview
+}
+
+func clean(s string) string {
+	return strings.Join(strings.Fields(html.UnescapeString(s)), " ")
+}
+
+func first(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n-1]) + "…"
+}
@@ -0,0 +1,135 @@
+package linkpreview
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+)
+
+const page = `<!doctype html>
+<html><head>
+<title>Plain   title</title>
+<meta property="og:title" content="Chirpy &amp; friends">
+<meta name='description' content='A plain description'>
+<meta property="og:image" content="/img/card.png">
+</head>
+<body><meta property="og:description" content="not in the head"></body></html>`
+
+func TestFetch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/old":
+			http.Redirect(w, r, "/post", http.StatusMovedPermanently)
+		case "/post":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte(page))
+		case "/untitled":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html><head></head><body>hi</body></html>"))
+		case "/json":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"title":"no"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	f := New(srv.Client(), time.Second, 1<<20)
+	preview, err := f.Fetch(context.Background(), srv.URL+"/old")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if preview.Title != "Chirpy & friends" {
+		t.Errorf("expected the og:title, got %q", preview.Title)
+	}
+	if preview.Description != "A plain description" {
+		t.Errorf("expected the description from the head, got %q", preview.Description)
+	}
+	if preview.ImageURL != srv.URL+"/img/card.png" {
+		t.Errorf("expected the image resolved against the page, got %q", preview.ImageURL)
+	}
+
+	if _, err := f.Fetch(context.Background(), srv.URL+"/untitled"); !errors.Is(err, ErrNoPreview) {
+		t.Errorf("expected ErrNoPreview, got %v", err)
+	}
+	for _, path := range []string{"/json", "/missing"} {
+		if _, err := f.Fetch(context.Background(), srv.URL+path); err == nil {
+			t.Errorf("expected %s to fail", path)
+		}
+	}
+	if _, err := f.Fetch(context.Background(), "file:///etc/passwd"); err == nil {
+		t.Errorf("expected non-http links to be refused")
+	}
+}
+
+func TestFetchLimits(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/slow":
+			select {
+			case <-r.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+		case "/big":
+			w.Write([]byte("<html><head>" + strings.Repeat(" ", 4096) + "<title>late</title></head></html>"))
+		}
+	}))
+	defer srv.Close()
+
+	f := New(srv.Client(), 100*time.Millisecond, 1024)
+	start := time.Now()
+	if _, err := f.Fetch(context.Background(), srv.URL+"/slow"); err == nil {
+		t.Errorf("expected a slow page to time out")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("expected the fetch to give up after its timeout, took %v", elapsed)
+	}
+	if _, err := f.Fetch(context.Background(), srv.URL+"/big"); !errors.Is(err, ErrNoPreview) {
+		t.Errorf("expected the title past the size limit to be ignored, got %v", err)
+	}
+}
+
+func TestNewClientRefusesPrivateAddresses(t *testing.T) {
+	hit := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hit = true
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(page))
+	}))
+	defer srv.Close()
+
+	f := New(NewClient(time.Second), time.Second, 1<<20)
+	_, err := f.Fetch(context.Background(), srv.URL)
+	if !errors.Is(err, ErrBlocked) {
+		t.Errorf("expected ErrBlocked for a loopback server, got %v", err)
+	}
+	if hit {
+		t.Errorf("expected the request never to reach the server")
+	}
+}
+
+func TestAllowed(t *testing.T) {
+	allowed := []string{"93.184.216.34", "1.1.1.1", "2606:4700:4700::1111"}
+	blocked := []string{
+		"127.0.0.1", "10.1.2.3", "172.16.0.1", "192.168.1.1", "169.254.169.254",
+		"100.64.0.1", "0.0.0.0", "224.0.0.1", "255.255.255.255",
+		"::1", "::", "fe80::1", "fd00::1", "::ffff:127.0.0.1", "64:ff9b::a00:1",
+	}
+	for _, s := range allowed {
+		if !Allowed(netip.MustParseAddr(s)) {
+			t.Errorf("expected %s to be allowed", s)
+		}
+	}
+	for _, s := range blocked {
+		if Allowed(netip.MustParseAddr(s)) {
+			t.Errorf("expected %s to be blocked", s)
+		}
+	}
+}
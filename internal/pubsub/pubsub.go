@@ -0,0 +1,56 @@
+package pubsub
+
+import "sync"
+
+// Broker hands every published message to each current subscriber. Publish
+// never waits on a subscriber: one whose buffer is full is dropped and its
+// channel closed, and it has to catch up on what it missed some other way.
+type Broker[T any] struct {
+	mu   sync.Mutex
+	subs map[*Subscription[T]]chan T
+}
+
+// Subscription receives messages on C until it is closed, either by Close
+// or by the broker when it falls behind.
+type Subscription[T any] struct {
+	C      <-chan T
+	broker *Broker[T]
+}
+
+func New[T any]() *Broker[T] {
+	return &Broker[T]{subs: map[*Subscription[T]]chan T{}}
+}
+
+func (b *Broker[T]) Subscribe(buffer int) *Subscription[T] {
+	c := make(chan T, buffer)
+	sub := &Subscription[T]{C: c, broker: b}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subs[sub] = c
+	return sub
+}
+
+func (b *Broker[T]) Publish(msg T) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for sub, c := range b.subs {
+		select {
+		case c <- msg:
+		default:
+			delete(b.subs, sub)
+			close(c)
+		}
+	}
+}
+
+// Close unsubscribes. It is safe to call more than once, and after the
+// broker has dropped the subscription.
+func (s *Subscription[T]) Close() {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+	if c, ok := s.broker.subs[s]; ok {
+		delete(s.broker.subs, s)
+		close(c)
+	}
+}
@@ -0,0 +1,50 @@
+package pubsub
+
+import "testing"
+
+func TestPublish(t *testing.T) {
+	b := New[int]()
+	first := b.Subscribe(2)
+	second := b.Subscribe(2)
+
+	b.Publish(1)
+	for _, sub := range []*Subscription[int]{first, second} {
+		if got := <-sub.C; got != 1 {
+			t.Errorf("expected 1, got %d", got)
+		}
+	}
+
+	second.Close()
+	second.Close()
+	if _, ok := <-second.C; ok {
+		t.Errorf("expected a closed subscription to have no more messages")
+	}
+
+	b.Publish(2)
+	if got := <-first.C; got != 2 {
+		t.Errorf("expected 2, got %d", got)
+	}
+}
+
+func TestSlowSubscriberIsDropped(t *testing.T) {
+	b := New[int]()
+	slow := b.Subscribe(1)
+	fast := b.Subscribe(3)
+
+	for i := 1; i <= 3; i++ {
+		b.Publish(i)
+	}
+
+	if got := <-slow.C; got != 1 {
+		t.Errorf("expected the buffered message to be kept, got %d", got)
+	}
+	if _, ok := <-slow.C; ok {
+		t.Errorf("expected the slow subscriber to be dropped")
+	}
+	for i := 1; i <= 3; i++ {
+		if got := <-fast.C; got != i {
+			t.Errorf("expected %d, got %d", i, got)
+		}
+	}
+	slow.Close()
+}
@@ -0,0 +1,35 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAllow(t *testing.T) {
+	now := time.Now()
+	l := New(2, 3)
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if ok, _ := l.Allow("alice"); !ok {
+			t.Fatalf("request %d should fit in the burst", i)
+		}
+	}
+
+	ok, wait := l.Allow("alice")
+	if ok {
+		t.Fatalf("expected the fourth request to be limited")
+	}
+	if wait != 500*time.Millisecond {
+		t.Errorf("expected to wait 500ms, got %v", wait)
+	}
+
+	if ok, _ := l.Allow("bob"); !ok {
+		t.Errorf("expected keys to be limited separately")
+	}
+
+	now = now.Add(500 * time.Millisecond)
+	if ok, _ := l.Allow("alice"); !ok {
+		t.Errorf("expected a token after waiting")
+	}
+}
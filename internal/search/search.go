@@ -0,0 +1,104 @@
+package search
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+
+	"chirpy/internal/entities"
+)
+
+// Query is a parsed search box input. TSQuery is ready to hand to Postgres'
+// to_tsquery; Author is the handle from a from: filter, if there was one.
+type Query struct {
+	TSQuery string
+	Author  string
+}
+
+// Parse turns user input into a tsquery. All terms must match. "Quoted
+// words" must appear next to each other, a trailing * matches any word with
+// that prefix, and from:handle limits results to one author. Everything
+// that isn't a letter or digit is treated as a word boundary, so user input
+// can never produce tsquery syntax of its own.
+func Parse(input string) (Query, error) {
+	q := Query{}
+	terms := []string{}
+
+	for _, token := range tokenize(input) {
+		if token.phrase {
+			if term := phrase(token.text, false); term != "" {
+				terms = append(terms, term)
+			}
+			continue
+		}
+
+		if rest, ok := strings.CutPrefix(token.text, "from:"); ok {
+			handle := strings.TrimPrefix(rest, "@")
+			if !entities.ValidHandle(handle) {
+				return q, errors.New("from: needs a valid handle")
+			}
+			q.Author = handle
+			continue
+		}
+
+		prefix := strings.HasSuffix(token.text, "*")
+		if term := phrase(strings.TrimSuffix(token.text, "*"), prefix); term != "" {
+			terms = append(terms, term)
+		}
+	}
+
+	if len(terms) == 0 {
+		return q, errors.New("search needs at least one word")
+	}
+	q.TSQuery = strings.Join(terms, " & ")
+	return q, nil
+}
+
+type token struct {
+	text   string
+	phrase bool
+}
+
+func tokenize(input string) []token {
+	tokens := []token{}
+	for {
+		input = strings.TrimSpace(input)
+		if input == "" {
+			return tokens
+		}
+		if input[0] == '"' {
+			end := strings.IndexByte(input[1:], '"')
+			if end < 0 {
+				tokens = append(tokens, token{text: input[1:], phrase: true})
+				return tokens
+			}
+			tokens = append(tokens, token{text: input[1 : end+1], phrase: true})
+			input = input[end+2:]
+			continue
+		}
+		end := strings.IndexFunc(input, func(r rune) bool { return unicode.IsSpace(r) || r == '"' })
+		if end < 0 {
+			end = len(input)
+		}
+		tokens = append(tokens, token{text: input[:end]})
+		input = input[end:]
+	}
+}
+
+// phrase joins the words in text with the followed-by operator. With prefix
+// set, the last word matches as a prefix.
+func phrase(text string, prefix bool) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) == 0 {
+		return ""
+	}
+	if prefix {
+		words[len(words)-1] += ":*"
+	}
+	if len(words) == 1 {
+		return words[0]
+	}
+	return "(" + strings.Join(words, " <-> ") + ")"
+}
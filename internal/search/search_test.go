@@ -0,0 +1,49 @@
+package search
+
+import (
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		input   string
+		tsquery string
+		author  string
+	}{
+		{input: "hello world", tsquery: "hello & world"},
+		{input: `"good morning" chirpy`, tsquery: "(good <-> morning) & chirpy"},
+		{input: "chirp* from:alice", tsquery: "chirp:*", author: "alice"},
+		{input: "from:@Bob it's", tsquery: "(it <-> s)", author: "Bob"},
+		{input: "a:* & | ! (b)", tsquery: "a:* & b"},
+	}
+
+	for _, c := range cases {
+		q, err := Parse(c.input)
+		if err != nil {
+			t.Errorf("%q: unexpected error %v", c.input, err)
+			continue
+		}
+		if q.TSQuery != c.tsquery {
+			t.Errorf("%q: expected tsquery %q, got %q", c.input, c.tsquery, q.TSQuery)
+		}
+		if q.Author != c.author {
+			t.Errorf("%q: expected author %q, got %q", c.input, c.author, q.Author)
+		}
+	}
+}
+
+func TestParseRejectsEmptySearch(t *testing.T) {
+	for _, input := range []string{"", "   ", "from:alice", `"!!"`} {
+		_, err := Parse(input)
+		if err == nil {
+			t.Errorf("%q: expected an error", input)
+		}
+	}
+}
+
+func TestParseRejectsBadHandle(t *testing.T) {
+	_, err := Parse("hello from:not-a-handle")
+	if err == nil {
+		t.Errorf("expected an error for an invalid handle")
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"chirpy/internal/database"
+	"context"
+	"log"
+	"time"
+)
+
+const (
+	linkPreviewTimeout  = 5 * time.Second
+	maxLinkPreviewBytes = 512 << 10
+	linkPreviewBatch    = 10
+)
+
+// runLinkPreviewer fetches the previews queued by recordLinks. Each link
+// is tried once: a page that fails or has no title is marked failed and
+// shown without a card. A link claimed by a worker that died before
+// saving it is claimed again once the claim is five minutes old.
+func (cfg *apiConfig) runLinkPreviewer(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		for {
+			n, err := cfg.fetchLinkPreviews(ctx)
+			if err != nil {
+				log.Printf("link previews: %v", err)
+			}
+			if n < linkPreviewBatch {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// fetchLinkPreviews claims a batch of pending links and fetches them,
+// reporting how many it claimed.
+func (cfg *apiConfig) fetchLinkPreviews(ctx context.Context) (int, error) {
+	urls, err := cfg.dbQueries.ClaimLinkPreviews(ctx, linkPreviewBatch)
+	if err != nil {
+		return 0, err
+	}
+	for _, url := range urls {
+		params := database.SaveLinkPreviewParams{Url: url}
+		preview, err := cfg.previews.Fetch(ctx, url)
+		if err != nil {
+			params.Failed = true
+		} else {
+			params.Title = preview.Title
+			params.Description = preview.Description
+			params.ImageUrl = preview.ImageURL
+		}
+		err = cfg.dbQueries.SaveLinkPreview(ctx, params)
+		if err != nil {
+			return len(urls), err
+		}
+	}
+	return len(urls), nil
+}
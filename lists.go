@@ -0,0 +1,576 @@
+package main
+
+import (
+	"chirpy/internal/database"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+const (
+	maxListNameLength        = 50
+	maxListDescriptionLength = 160
+	maxListMembers           = 500
+)
+
+func checkListName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", errors.New("name can't be empty")
+	}
+	if utf8.RuneCountInString(name) > maxListNameLength {
+		return "", fmt.Errorf("name must be at most %d characters", maxListNameLength)
+	}
+	return name, nil
+}
+
+func checkListDescription(description string) (string, error) {
+	description = strings.TrimSpace(description)
+	if utf8.RuneCountInString(description) > maxListDescriptionLength {
+		return "", fmt.Errorf("description must be at most %d characters", maxListDescriptionLength)
+	}
+	return description, nil
+}
+
+func mapList(l database.List) List {
+	return List{
+		ID:          l.ID,
+		CreatedAt:   l.CreatedAt,
+		UpdatedAt:   l.UpdatedAt,
+		OwnerID:     l.OwnerID,
+		Name:        l.Name,
+		Description: l.Description,
+		Private:     l.Private,
+	}
+}
+
+// visibleList loads the list in the path for the viewer. Private lists, and
+// lists whose owner has a block with the viewer, are reported as missing so
+// their existence doesn't leak.
+func (cfg *apiConfig) visibleList(r *http.Request) (database.List, uuid.UUID, int, error) {
+	viewerUUID, err := cfg.viewer(r)
+	if err != nil {
+		return database.List{}, viewerUUID, 401, err
+	}
+
+	listID, err := uuid.Parse(r.PathValue("listid"))
+	if err != nil {
+		return database.List{}, viewerUUID, 400, err
+	}
+
+	list, err := cfg.dbQueries.GetList(r.Context(), listID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return list, viewerUUID, 404, errors.New("list not found")
+	}
+	if err != nil {
+		return list, viewerUUID, 500, err
+	}
+	if list.OwnerID == viewerUUID {
+		return list, viewerUUID, 0, nil
+	}
+	if list.Private {
+		return list, viewerUUID, 404, errors.New("list not found")
+	}
+
+	blocked, err := cfg.dbQueries.BlockedBetween(r.Context(), database.BlockedBetweenParams{
+		UserA: list.OwnerID,
+		UserB: viewerUUID,
+	})
+	if err != nil {
+		return list, viewerUUID, 500, err
+	}
+	if blocked {
+		return list, viewerUUID, 404, errors.New("list not found")
+	}
+	return list, viewerUUID, 0, nil
+}
+
+func (cfg *apiConfig) CreateList(w http.ResponseWriter, r *http.Request) {
+	type cred struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+		Private     bool   `json:"private"`
+	}
+
+	userUUID, err := cfg.authenticate(r)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 401, res)
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := cred{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 400, res)
+		return
+	}
+
+	name, err := checkListName(params.Name)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 400, res)
+		return
+	}
+	description, err := checkListDescription(params.Description)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 400, res)
+		return
+	}
+
+	list, err := cfg.dbQueries.CreateList(r.Context(), database.CreateListParams{
+		OwnerID:     userUUID,
+		Name:        name,
+		Description: description,
+		Private:     params.Private,
+	})
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	jsr, err := json.Marshal(mapList(list))
+	if err != nil {
+		panic(err)
+	}
+	respondWithJson(w, 201, jsr)
+}
+
+// GetLists returns the caller's own lists, private ones included.
+func (cfg *apiConfig) GetLists(w http.ResponseWriter, r *http.Request) {
+	userUUID, err := cfg.authenticate(r)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 401, res)
+		return
+	}
+
+	rows, err := cfg.dbQueries.GetLists(r.Context(), userUUID)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	lists := []List{}
+	for _, row := range rows {
+		lists = append(lists, List{
+			ID:           row.ID,
+			CreatedAt:    row.CreatedAt,
+			UpdatedAt:    row.UpdatedAt,
+			OwnerID:      row.OwnerID,
+			Name:         row.Name,
+			Description:  row.Description,
+			Private:      row.Private,
+			MembersCount: row.MembersCount,
+		})
+	}
+
+	jsr, err := json.Marshal(lists)
+	if err != nil {
+		panic(err)
+	}
+	respondWithJson(w, 200, jsr)
+}
+
+func (cfg *apiConfig) GetList(w http.ResponseWriter, r *http.Request) {
+	list, _, status, err := cfg.visibleList(r)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, status, res)
+		return
+	}
+
+	count, err := cfg.dbQueries.CountListMembers(r.Context(), list.ID)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	l := mapList(list)
+	l.MembersCount = count
+	jsr, err := json.Marshal(l)
+	if err != nil {
+		panic(err)
+	}
+	respondWithJson(w, 200, jsr)
+}
+
+func (cfg *apiConfig) UpdateList(w http.ResponseWriter, r *http.Request) {
+	type listUpdate struct {
+		Name        *string `json:"name"`
+		Description *string `json:"description"`
+		Private     *bool   `json:"private"`
+	}
+
+	userUUID, err := cfg.authenticate(r)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 401, res)
+		return
+	}
+
+	listID, err := uuid.Parse(r.PathValue("listid"))
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 400, res)
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	update := listUpdate{}
+	err = decoder.Decode(&update)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 400, res)
+		return
+	}
+
+	current, err := cfg.dbQueries.GetList(r.Context(), listID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && current.OwnerID != userUUID) {
+		formJsonResponse(w, 404, `{"error":"list not found"}`)
+		return
+	}
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	params := database.UpdateListParams{
+		ID:          listID,
+		OwnerID:     userUUID,
+		Name:        current.Name,
+		Description: current.Description,
+		Private:     current.Private,
+	}
+	if update.Name != nil {
+		params.Name, err = checkListName(*update.Name)
+		if err != nil {
+			res := fmt.Sprintf(`{"error":"%v"}`, err)
+			formJsonResponse(w, 400, res)
+			return
+		}
+	}
+	if update.Description != nil {
+		params.Description, err = checkListDescription(*update.Description)
+		if err != nil {
+			res := fmt.Sprintf(`{"error":"%v"}`, err)
+			formJsonResponse(w, 400, res)
+			return
+		}
+	}
+	if update.Private != nil {
+		params.Private = *update.Private
+	}
+
+	list, err := cfg.dbQueries.UpdateList(r.Context(), params)
+	if errors.Is(err, sql.ErrNoRows) {
+		formJsonResponse(w, 404, `{"error":"list not found"}`)
+		return
+	}
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	count, err := cfg.dbQueries.CountListMembers(r.Context(), list.ID)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	l := mapList(list)
+	l.MembersCount = count
+	jsr, err := json.Marshal(l)
+	if err != nil {
+		panic(err)
+	}
+	respondWithJson(w, 200, jsr)
+}
+
+func (cfg *apiConfig) DeleteList(w http.ResponseWriter, r *http.Request) {
+	userUUID, err := cfg.authenticate(r)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 401, res)
+		return
+	}
+
+	listID, err := uuid.Parse(r.PathValue("listid"))
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 400, res)
+		return
+	}
+
+	n, err := cfg.dbQueries.DeleteList(r.Context(), database.DeleteListParams{
+		ID:      listID,
+		OwnerID: userUUID,
+	})
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+	if n == 0 {
+		formJsonResponse(w, 404, `{"error":"list not found"}`)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+// GetListMembers pages through a list's members, most recently added first.
+// Accounts with a block against the viewer are left out.
+func (cfg *apiConfig) GetListMembers(w http.ResponseWriter, r *http.Request) {
+	list, viewerUUID, status, err := cfg.visibleList(r)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, status, res)
+		return
+	}
+
+	after, limit, err := parsePage(r, newestFirst)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 400, res)
+		return
+	}
+
+	rows, err := cfg.dbQueries.GetListMembers(r.Context(), database.GetListMembersParams{
+		ListID:          list.ID,
+		ViewerID:        viewerUUID,
+		BeforeCreatedAt: after.CreatedAt,
+		BeforeID:        after.ID,
+		PageSize:        limit,
+	})
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	profiles := []Profile{}
+	for _, row := range rows {
+		profiles = append(profiles, Profile{
+			ID:          row.ID,
+			CreatedAt:   row.CreatedAt,
+			Handle:      row.Handle,
+			DisplayName: row.DisplayName,
+			Bio:         row.Bio,
+			AvatarURL:   row.AvatarUrl,
+			Private:     row.Private,
+		})
+	}
+	if len(rows) == int(limit) {
+		last := rows[len(rows)-1]
+		setNextPage(w, r, pageCursor{CreatedAt: last.AddedAt, ID: last.ID})
+	}
+
+	jsr, err := json.Marshal(profiles)
+	if err != nil {
+		panic(err)
+	}
+	respondWithJson(w, 200, jsr)
+}
+
+// AddListMember puts the account in the path on one of the caller's lists.
+// Adding someone already on it succeeds without changing anything.
+func (cfg *apiConfig) AddListMember(w http.ResponseWriter, r *http.Request) {
+	userUUID, err := cfg.authenticate(r)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 401, res)
+		return
+	}
+
+	listID, err := uuid.Parse(r.PathValue("listid"))
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 400, res)
+		return
+	}
+
+	target, err := cfg.dbQueries.GetUserByHandle(r.Context(), r.PathValue("handle"))
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 404, res)
+		return
+	}
+
+	blocked, err := cfg.dbQueries.BlockedBetween(r.Context(), database.BlockedBetweenParams{
+		UserA: userUUID,
+		UserB: target.ID,
+	})
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+	if blocked {
+		formJsonResponse(w, 403, `{"error":"you can't add this account"}`)
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	list, err := qtx.LockList(r.Context(), listID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && list.OwnerID != userUUID) {
+		formJsonResponse(w, 404, `{"error":"list not found"}`)
+		return
+	}
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	count, err := qtx.CountListMembers(r.Context(), listID)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+	if count >= maxListMembers {
+		res := fmt.Sprintf(`{"error":"a list can have at most %d members"}`, maxListMembers)
+		formJsonResponse(w, 409, res)
+		return
+	}
+
+	err = qtx.AddListMember(r.Context(), database.AddListMemberParams{
+		ListID: listID,
+		UserID: target.ID,
+	})
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) RemoveListMember(w http.ResponseWriter, r *http.Request) {
+	userUUID, err := cfg.authenticate(r)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 401, res)
+		return
+	}
+
+	listID, err := uuid.Parse(r.PathValue("listid"))
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 400, res)
+		return
+	}
+
+	list, err := cfg.dbQueries.GetList(r.Context(), listID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && list.OwnerID != userUUID) {
+		formJsonResponse(w, 404, `{"error":"list not found"}`)
+		return
+	}
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	target, err := cfg.dbQueries.GetUserByHandle(r.Context(), r.PathValue("handle"))
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 404, res)
+		return
+	}
+
+	n, err := cfg.dbQueries.RemoveListMember(r.Context(), database.RemoveListMemberParams{
+		ListID: listID,
+		UserID: target.ID,
+	})
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+	if n == 0 {
+		formJsonResponse(w, 404, `{"error":"account is not on this list"}`)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+// ListTimeline is the home timeline built from a list's members instead of
+// the viewer's follows. The same visibility, block and mute rules apply, so
+// a private member's chirps only show to viewers who follow them.
+func (cfg *apiConfig) ListTimeline(w http.ResponseWriter, r *http.Request) {
+	list, viewerUUID, status, err := cfg.visibleList(r)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, status, res)
+		return
+	}
+
+	after, limit, err := parsePage(r, newestFirst)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 400, res)
+		return
+	}
+
+	dbChirps, err := cfg.dbQueries.GetListTimeline(r.Context(), database.GetListTimelineParams{
+		ListID:          list.ID,
+		ViewerID:        viewerUUID,
+		BeforeCreatedAt: after.CreatedAt,
+		BeforeID:        after.ID,
+		PageSize:        limit,
+	})
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+	chirps := []Chirp{}
+	for _, c := range dbChirps {
+		chirps = append(chirps, mapChirp(c))
+	}
+	err = cfg.attachEntities(r.Context(), chirps, viewerUUID)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	if len(chirps) == int(limit) {
+		last := chirps[len(chirps)-1]
+		setNextPage(w, r, pageCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+	jsr, err := json.Marshal(chirps)
+	if err != nil {
+		panic(err)
+	}
+	respondWithJson(w, 200, jsr)
+}
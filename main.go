@@ -1,16 +1,25 @@
 package main
 
 import (
+	"chirpy/internal/activitypub"
 	"chirpy/internal/database"
+	"chirpy/internal/linkpreview"
+	"chirpy/internal/pubsub"
+	"chirpy/internal/ratelimit"
+	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
 	"os"
 	"strings"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/joho/godotenv"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 func main() {
@@ -24,14 +33,51 @@ func main() {
 	birdcfg := apiConfig{}
 	birdcfg.Platform = os.Getenv("PLATFORM")
 	birdcfg.Secret = os.Getenv("SECRET")
+	birdcfg.publicURL = strings.TrimSuffix(os.Getenv("PUBLIC_URL"), "/")
+	birdcfg.db = db
 	birdcfg.dbQueries = database.New(db)
+	birdcfg.timelines = fanoutOnRead{dbQueries: birdcfg.dbQueries}
+	birdcfg.searchLimiter = ratelimit.New(searchRate, searchBurst)
+	birdcfg.trends, err = trendsConfigFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	birdcfg.blobs, err = blobStoreFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	schedulerInterval, err := durationFromEnv("SCHEDULER_INTERVAL", 15*time.Second)
+	if err != nil {
+		log.Fatal(err)
+	}
+	previewInterval, err := durationFromEnv("LINK_PREVIEW_INTERVAL", 10*time.Second)
+	if err != nil {
+		log.Fatal(err)
+	}
+	birdcfg.previews = linkpreview.New(linkpreview.NewClient(linkPreviewTimeout), linkPreviewTimeout, maxLinkPreviewBytes)
+	birdcfg.federation = activitypub.NewClient(linkpreview.NewClient(federationTimeout), "chirpy")
+	deliveryInterval, err := durationFromEnv("DELIVERY_INTERVAL", 5*time.Second)
+	if err != nil {
+		log.Fatal(err)
+	}
+	birdcfg.restoreWindow, err = durationFromEnv("CHIRP_RESTORE_WINDOW", defaultRestoreWindow)
+	if err != nil {
+		log.Fatal(err)
+	}
+	birdcfg.chirpStream = pubsub.New[database.ChirpEvent]()
+	birdcfg.notificationStream = pubsub.New[database.Notification]()
+	birdcfg.eventChirps = newChirpEventCache(&birdcfg)
+	err = birdcfg.startEvents(context.Background(), os.Getenv("STREAM_BACKEND"), dbURL)
+	if err != nil {
+		log.Fatal(err)
+	}
 	var birdmux = http.NewServeMux()
 	birdmux.Handle("/app/", http.StripPrefix("/app", birdcfg.mwMetricsInc(http.FileServer(http.Dir(".")))))
 	birdmux.HandleFunc("GET /admin/healthz", readiness)
 	birdmux.HandleFunc("GET /admin/metrics", birdcfg.metrics)
 	birdmux.HandleFunc("POST /admin/reset", birdcfg.ressetmetrics)
-	birdmux.HandleFunc("POST /api/users", birdcfg.createUser)
-	birdmux.HandleFunc("POST /api/chirps", birdcfg.createChirp)
+	birdmux.HandleFunc("POST /api/users", birdcfg.idempotent(birdcfg.createUser))
+	birdmux.HandleFunc("POST /api/chirps", birdcfg.idempotent(birdcfg.createChirp))
 	birdmux.HandleFunc("GET /api/chirps", birdcfg.GetChirps)
 	birdmux.HandleFunc("GET /api/chirps/{chirpid}", birdcfg.GetChirpByID)
 	birdmux.HandleFunc("POST /api/login", birdcfg.Login)
@@ -39,6 +85,90 @@ func main() {
 	birdmux.HandleFunc("POST /api/revoke", birdcfg.Revoke)
 	birdmux.HandleFunc("PUT /api/users", birdcfg.UpdatePassword)
 	birdmux.HandleFunc("DELETE /api/chirps/{chirpid}", birdcfg.DeleteChirp)
+	birdmux.HandleFunc("GET /api/users/{handle}", birdcfg.GetProfile)
+	birdmux.HandleFunc("PATCH /api/users/me", birdcfg.UpdateProfile)
+	birdmux.HandleFunc("POST /api/users/{handle}/follow", birdcfg.Follow)
+	birdmux.HandleFunc("DELETE /api/users/{handle}/follow", birdcfg.Unfollow)
+	birdmux.HandleFunc("GET /api/users/{handle}/followers", birdcfg.GetFollowers)
+	birdmux.HandleFunc("GET /api/users/{handle}/following", birdcfg.GetFollowing)
+	birdmux.HandleFunc("GET /api/timeline/home", birdcfg.HomeTimeline)
+	birdmux.HandleFunc("POST /api/users/{handle}/block", birdcfg.Block)
+	birdmux.HandleFunc("DELETE /api/users/{handle}/block", birdcfg.Unblock)
+	birdmux.HandleFunc("GET /api/blocks", birdcfg.GetBlocks)
+	birdmux.HandleFunc("POST /api/users/{handle}/mute", birdcfg.Mute)
+	birdmux.HandleFunc("DELETE /api/users/{handle}/mute", birdcfg.Unmute)
+	birdmux.HandleFunc("GET /api/mutes", birdcfg.GetMutes)
+	birdmux.HandleFunc("GET /api/follow-requests", birdcfg.GetFollowRequests)
+	birdmux.HandleFunc("POST /api/follow-requests/{handle}/approve", birdcfg.ApproveFollowRequest)
+	birdmux.HandleFunc("POST /api/follow-requests/{handle}/reject", birdcfg.RejectFollowRequest)
+	birdmux.HandleFunc("GET /api/search/chirps", birdcfg.rateLimit(birdcfg.searchLimiter, birdcfg.SearchChirps))
+	birdmux.HandleFunc("GET /api/search/users", birdcfg.rateLimit(birdcfg.searchLimiter, birdcfg.SearchUsers))
+	birdmux.HandleFunc("GET /api/trends", birdcfg.GetTrends)
+	birdmux.HandleFunc("GET /api/stream/chirps", birdcfg.StreamChirps)
+	birdmux.HandleFunc("GET /api/ws", birdcfg.Connect)
+	birdmux.HandleFunc("GET /api/notifications", birdcfg.GetNotifications)
+	birdmux.HandleFunc("POST /api/notifications/read", birdcfg.MarkNotificationsRead)
+	birdmux.HandleFunc("POST /api/notifications/read-all", birdcfg.MarkAllNotificationsRead)
+	birdmux.HandleFunc("POST /api/media", birdcfg.UploadMedia)
+	birdmux.HandleFunc("GET /api/media/{id}", birdcfg.ServeMedia)
+	birdmux.HandleFunc("GET /api/media/{id}/thumbnail", birdcfg.ServeThumbnail)
+	birdmux.HandleFunc("POST /api/conversations", birdcfg.CreateConversation)
+	birdmux.HandleFunc("GET /api/conversations", birdcfg.GetConversations)
+	birdmux.HandleFunc("GET /api/conversations/{id}/messages", birdcfg.GetMessages)
+	birdmux.HandleFunc("POST /api/conversations/{id}/messages", birdcfg.SendMessage)
+	birdmux.HandleFunc("POST /api/conversations/{id}/read", birdcfg.MarkConversationRead)
+	birdmux.HandleFunc("POST /api/chirps/{chirpid}/poll/votes", birdcfg.VotePoll)
+	birdmux.HandleFunc("POST /api/drafts", birdcfg.CreateDraft)
+	birdmux.HandleFunc("GET /api/drafts", birdcfg.GetDrafts)
+	birdmux.HandleFunc("PATCH /api/drafts/{draftid}", birdcfg.UpdateDraft)
+	birdmux.HandleFunc("DELETE /api/drafts/{draftid}", birdcfg.DeleteDraft)
+	birdmux.HandleFunc("POST /api/drafts/{draftid}/publish", birdcfg.PublishDraft)
+	birdmux.HandleFunc("POST /api/chirps/{chirpid}/restore", birdcfg.RestoreChirp)
+	birdmux.HandleFunc("POST /api/chirps/{chirpid}/pin", birdcfg.PinChirp)
+	birdmux.HandleFunc("DELETE /api/chirps/{chirpid}/pin", birdcfg.UnpinChirp)
+	birdmux.HandleFunc("PUT /api/users/me/pins", birdcfg.ReorderPins)
+	birdmux.HandleFunc("POST /api/collections", birdcfg.CreateCollection)
+	birdmux.HandleFunc("GET /api/collections", birdcfg.GetCollections)
+	birdmux.HandleFunc("PATCH /api/collections/{collectionid}", birdcfg.RenameCollection)
+	birdmux.HandleFunc("DELETE /api/collections/{collectionid}", birdcfg.DeleteCollection)
+	birdmux.HandleFunc("PUT /api/chirps/{chirpid}/bookmark", birdcfg.Bookmark)
+	birdmux.HandleFunc("DELETE /api/chirps/{chirpid}/bookmark", birdcfg.Unbookmark)
+	birdmux.HandleFunc("GET /api/bookmarks", birdcfg.GetBookmarks)
+	birdmux.HandleFunc("POST /api/lists", birdcfg.CreateList)
+	birdmux.HandleFunc("GET /api/lists", birdcfg.GetLists)
+	birdmux.HandleFunc("GET /api/lists/{listid}", birdcfg.GetList)
+	birdmux.HandleFunc("PATCH /api/lists/{listid}", birdcfg.UpdateList)
+	birdmux.HandleFunc("DELETE /api/lists/{listid}", birdcfg.DeleteList)
+	birdmux.HandleFunc("GET /api/lists/{listid}/members", birdcfg.GetListMembers)
+	birdmux.HandleFunc("PUT /api/lists/{listid}/members/{handle}", birdcfg.AddListMember)
+	birdmux.HandleFunc("DELETE /api/lists/{listid}/members/{handle}", birdcfg.RemoveListMember)
+	birdmux.HandleFunc("GET /api/lists/{listid}/timeline", birdcfg.ListTimeline)
+	birdmux.HandleFunc("GET /api/users/{handle}/chirps", birdcfg.GetUserChirps)
+	birdmux.HandleFunc("GET /api/hashtags/{tag}/chirps", birdcfg.GetHashtagChirps)
+	birdmux.HandleFunc("GET /users/{handle}/feed.atom", birdcfg.UserFeed(feedAtom))
+	birdmux.HandleFunc("GET /users/{handle}/feed.rss", birdcfg.UserFeed(feedRSS))
+	birdmux.HandleFunc("GET /hashtags/{tag}/feed.atom", birdcfg.HashtagFeed(feedAtom))
+	birdmux.HandleFunc("GET /hashtags/{tag}/feed.rss", birdcfg.HashtagFeed(feedRSS))
+	if birdcfg.federating() {
+		birdmux.HandleFunc("GET /.well-known/webfinger", birdcfg.WebFinger)
+		birdmux.HandleFunc("GET /ap/users/{userid}", birdcfg.GetActor)
+		birdmux.HandleFunc("GET /ap/users/{userid}/outbox", birdcfg.GetOutbox)
+		birdmux.HandleFunc("GET /ap/users/{userid}/followers", birdcfg.GetActorFollowers)
+		birdmux.HandleFunc("POST /ap/users/{userid}/inbox", birdcfg.Inbox)
+		birdmux.HandleFunc("POST /ap/inbox", birdcfg.Inbox)
+		birdmux.HandleFunc("GET /ap/chirps/{chirpid}", birdcfg.GetNote)
+		birdmux.HandleFunc("POST /api/federation/follows", birdcfg.FollowRemote)
+		birdmux.HandleFunc("GET /api/federation/follows", birdcfg.GetRemoteFollows)
+		birdmux.HandleFunc("DELETE /api/federation/follows/{account}", birdcfg.UnfollowRemote)
+		go birdcfg.runDeliveryWorker(context.Background(), deliveryInterval)
+	}
+
+	go birdcfg.runTrendsWorker(context.Background())
+	go birdcfg.runMediaSweeper(context.Background())
+	go birdcfg.runScheduler(context.Background(), schedulerInterval)
+	go birdcfg.runChirpPurger(context.Background())
+	go birdcfg.runLinkPreviewer(context.Background(), previewInterval)
+	go birdcfg.runIdempotencyPurger(context.Background())
 
 	var birdserver http.Server
 	birdserver.Addr = ":8080"
@@ -99,11 +229,66 @@ func StripProfane(content string) string {
 
 func mapChirp(c database.Chirp) Chirp {
 	chirp := Chirp{
-		ID:        c.ID,
-		CreatedAt: c.CreatedAt,
-		UpdatedAt: c.UpdatedAt,
-		Body:      c.Body,
-		User_id:   c.UserID,
+		ID:             c.ID,
+		CreatedAt:      c.CreatedAt,
+		UpdatedAt:      c.UpdatedAt,
+		Body:           c.Body,
+		User_id:        c.UserID,
+		Visibility:     c.Visibility,
+		ContentWarning: c.ContentWarning,
+		Entities: ChirpEntities{
+			Mentions: []MentionEntity{},
+			Hashtags: []HashtagEntity{},
+			Links:    []LinkEntity{},
+		},
+		Media: []Media{},
 	}
 	return chirp
 }
+
+func mapProfile(u database.User) Profile {
+	profile := Profile{
+		ID:          u.ID,
+		CreatedAt:   u.CreatedAt,
+		Handle:      u.Handle,
+		DisplayName: u.DisplayName,
+		Bio:         u.Bio,
+		AvatarURL:   u.AvatarUrl,
+		Private:     u.Private,
+	}
+	return profile
+}
+
+// defaultHandle gives users who sign up without choosing a handle one they
+// can change later.
+func defaultHandle() string {
+	return "user_" + strings.ReplaceAll(uuid.NewString(), "-", "")[:10]
+}
+
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+func isCheckViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23514"
+}
+
+func isNotNullViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23502"
+}
+
+// durationFromEnv reads a Go duration such as "15m" from the environment.
+func durationFromEnv(name string, fallback time.Duration) (time.Duration, error) {
+	s := os.Getenv(name)
+	if s == "" {
+		return fallback, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("%s must be a positive duration like 15m, got %q", name, s)
+	}
+	return d, nil
+}
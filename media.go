@@ -0,0 +1,346 @@
+package main
+
+import (
+	"chirpy/internal/blobstore"
+	"chirpy/internal/database"
+	"chirpy/internal/imaging"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	maxUploadBytes = 5 << 20
+	maxChirpMedia  = 4
+	// unattachedMediaTTL is how long an upload can wait to be attached to
+	// a chirp before it's deleted. Media of deleted chirps goes the same way.
+	unattachedMediaTTL = 24 * time.Hour
+	mediaSweepBatch    = 100
+	// mediaMaxAge is how long a copy of some media may be reused before
+	// it's checked again. Its bytes never change, but who may see them
+	// does when the chirp is deleted, made private or its author blocks
+	// someone.
+	mediaMaxAge = 5 * time.Minute
+)
+
+// BlobStore holds the bytes of uploaded media. blobstore.Local suits a
+// single server; blobstore.S3 works with anything S3-compatible.
+type BlobStore interface {
+	Put(ctx context.Context, key, contentType string, body []byte) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// blobStoreFromEnv picks the store named by MEDIA_BACKEND, "local" (the
+// default, under MEDIA_DIR) or "s3".
+func blobStoreFromEnv() (BlobStore, error) {
+	switch backend := os.Getenv("MEDIA_BACKEND"); backend {
+	case "", "local":
+		dir := os.Getenv("MEDIA_DIR")
+		if dir == "" {
+			dir = "media"
+		}
+		return blobstore.NewLocal(dir), nil
+	case "s3":
+		store := blobstore.NewS3(
+			os.Getenv("S3_ENDPOINT"),
+			os.Getenv("S3_BUCKET"),
+			os.Getenv("S3_REGION"),
+			os.Getenv("S3_ACCESS_KEY_ID"),
+			os.Getenv("S3_SECRET_ACCESS_KEY"),
+		)
+		if store.Endpoint == "" || store.Bucket == "" || store.Region == "" {
+			return nil, errors.New("MEDIA_BACKEND=s3 needs S3_ENDPOINT, S3_BUCKET and S3_REGION")
+		}
+		return store, nil
+	default:
+		return nil, fmt.Errorf("MEDIA_BACKEND must be local or s3, got %q", backend)
+	}
+}
+
+var errMediaUnavailable = errors.New("media must be your own uploads and not attached to another chirp")
+
+func checkMediaIDs(ids []uuid.UUID) error {
+	if len(ids) > maxChirpMedia {
+		return fmt.Errorf("a chirp can have at most %d media", maxChirpMedia)
+	}
+	seen := map[uuid.UUID]bool{}
+	for _, id := range ids {
+		if seen[id] {
+			return fmt.Errorf("media %s is listed twice", id)
+		}
+		seen[id] = true
+	}
+	return nil
+}
+
+// attachMedia claims the author's uploads for a new chirp, in the order
+// they were given.
+func attachMedia(ctx context.Context, qtx *database.Queries, chirp database.Chirp, ids []uuid.UUID) error {
+	for i, id := range ids {
+		n, err := qtx.AttachMedia(ctx, database.AttachMediaParams{
+			ChirpID:  uuid.NullUUID{UUID: chirp.ID, Valid: true},
+			Position: int32(i),
+			ID:       id,
+			UserID:   chirp.UserID,
+		})
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return errMediaUnavailable
+		}
+	}
+	return nil
+}
+
+func mediaKey(id uuid.UUID) string {
+	return "media/" + id.String()
+}
+
+func thumbnailKey(id uuid.UUID) string {
+	return "media/" + id.String() + "-thumb"
+}
+
+func mapMedia(m database.Medium) Media {
+	return Media{
+		ID:           m.ID,
+		URL:          "/api/media/" + m.ID.String(),
+		ThumbnailURL: "/api/media/" + m.ID.String() + "/thumbnail",
+		ContentType:  m.ContentType,
+		Width:        m.Width,
+		Height:       m.Height,
+	}
+}
+
+// UploadMedia takes an image in the "file" field of a multipart form. It
+// can then be attached to a chirp by passing its id in media_ids.
+func (cfg *apiConfig) UploadMedia(w http.ResponseWriter, r *http.Request) {
+	userUUID, err := cfg.authenticate(r)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 401, res)
+		return
+	}
+
+	// Leave room for the multipart framing around the file.
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadBytes+64<<10)
+	file, _, err := r.FormFile("file")
+	var tooBig *http.MaxBytesError
+	if errors.As(err, &tooBig) {
+		res := fmt.Sprintf(`{"error":"uploads must be at most %d bytes"}`, maxUploadBytes)
+		formJsonResponse(w, 413, res)
+		return
+	}
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 400, res)
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxUploadBytes+1))
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 400, res)
+		return
+	}
+	if len(data) > maxUploadBytes {
+		res := fmt.Sprintf(`{"error":"uploads must be at most %d bytes"}`, maxUploadBytes)
+		formJsonResponse(w, 413, res)
+		return
+	}
+
+	full, thumb, err := imaging.Process(data)
+	if errors.Is(err, imaging.ErrUnsupported) {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 415, res)
+		return
+	}
+	if errors.Is(err, imaging.ErrTooLarge) {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 413, res)
+		return
+	}
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 400, res)
+		return
+	}
+
+	// The row goes in first, unattached, so the sweeper cleans up after
+	// an upload that fails halfway.
+	media, err := cfg.dbQueries.CreateMedia(r.Context(), database.CreateMediaParams{
+		UserID:               userUUID,
+		ContentType:          full.ContentType,
+		Width:                int32(full.Width),
+		Height:               int32(full.Height),
+		ThumbnailContentType: thumb.ContentType,
+	})
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	err = cfg.blobs.Put(r.Context(), mediaKey(media.ID), full.ContentType, full.Data)
+	if err == nil {
+		err = cfg.blobs.Put(r.Context(), thumbnailKey(media.ID), thumb.ContentType, thumb.Data)
+	}
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	jsr, err := json.Marshal(mapMedia(media))
+	if err != nil {
+		panic(err)
+	}
+	respondWithJson(w, 201, jsr)
+}
+
+func (cfg *apiConfig) ServeMedia(w http.ResponseWriter, r *http.Request) {
+	cfg.serveMedia(w, r, false)
+}
+
+func (cfg *apiConfig) ServeThumbnail(w http.ResponseWriter, r *http.Request) {
+	cfg.serveMedia(w, r, true)
+}
+
+// serveMedia sends media to whoever can see the chirp it's attached to, or
+// to the uploader before it's attached. It can be cached for a few
+// minutes, by anyone when the chirp is public and otherwise only by the
+// viewer's own browser, and after that revalidated against its ETag, which
+// only answers 304 to viewers who can still see it.
+func (cfg *apiConfig) serveMedia(w http.ResponseWriter, r *http.Request, thumbnail bool) {
+	viewerUUID, err := cfg.viewer(r)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 401, res)
+		return
+	}
+
+	mediaID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 400, res)
+		return
+	}
+	media, err := cfg.dbQueries.GetMedia(r.Context(), mediaID)
+	if errors.Is(err, sql.ErrNoRows) {
+		formJsonResponse(w, 404, `{"error":"media not found"}`)
+		return
+	}
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	public := false
+	if !media.ChirpID.Valid {
+		if media.UserID != viewerUUID {
+			formJsonResponse(w, 404, `{"error":"media not found"}`)
+			return
+		}
+	} else {
+		_, err = cfg.dbQueries.GetChirp(r.Context(), database.GetChirpParams{
+			ID:       media.ChirpID.UUID,
+			ViewerID: viewerUUID,
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			formJsonResponse(w, 404, `{"error":"media not found"}`)
+			return
+		}
+		if err != nil {
+			res := fmt.Sprintf(`{"error":"%v"}`, err)
+			formJsonResponse(w, 500, res)
+			return
+		}
+
+		public = viewerUUID == uuid.Nil
+		if !public {
+			_, err = cfg.dbQueries.GetChirp(r.Context(), database.GetChirpParams{
+				ID:       media.ChirpID.UUID,
+				ViewerID: uuid.Nil,
+			})
+			public = err == nil
+		}
+	}
+
+	key, contentType := mediaKey(media.ID), media.ContentType
+	if thumbnail {
+		key, contentType = thumbnailKey(media.ID), media.ThumbnailContentType
+	}
+
+	scope := "private"
+	if public {
+		scope = "public"
+	}
+	w.Header().Set("Cache-Control", fmt.Sprintf("%s, max-age=%d", scope, int(mediaMaxAge.Seconds())))
+	etag := `"` + key + `"`
+	w.Header().Set("ETag", etag)
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(304)
+		return
+	}
+
+	blob, err := cfg.blobs.Get(r.Context(), key)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+	defer blob.Close()
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(200)
+	io.Copy(w, blob)
+}
+
+// runMediaSweeper deletes media left unattached for unattachedMediaTTL.
+// The row goes first, and only if it's still unattached, since a chirp
+// may have taken it since it was listed. If the sweeper stops before the
+// blobs are deleted they are left behind, which is the cheaper mistake.
+func (cfg *apiConfig) runMediaSweeper(ctx context.Context) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		stale, err := cfg.dbQueries.GetUnattachedMedia(ctx, database.GetUnattachedMediaParams{
+			CreatedAt: time.Now().Add(-unattachedMediaTTL),
+			Limit:     mediaSweepBatch,
+		})
+		if err != nil {
+			log.Printf("media: %v", err)
+			continue
+		}
+		for _, media := range stale {
+			n, err := cfg.dbQueries.DeleteMedia(ctx, media.ID)
+			if err == nil && n > 0 {
+				err = cfg.blobs.Delete(ctx, mediaKey(media.ID))
+				if err == nil {
+					err = cfg.blobs.Delete(ctx, thumbnailKey(media.ID))
+				}
+			}
+			if err != nil {
+				log.Printf("media: %s: %v", media.ID, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"chirpy/internal/database"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// Kinds of notification. Chirpy has no replies or likes yet; when it does,
+// they should notify the same way.
+const (
+	notificationMention       = "mention"
+	notificationFollow        = "follow"
+	notificationFollowRequest = "follow_request"
+)
+
+const (
+	// maxGroupActors is how many of a group's actors are listed by profile.
+	// The rest only count towards actors_count.
+	maxGroupActors = 3
+	maxMarkRead    = 100
+)
+
+func (cfg *apiConfig) mapNotification(ctx context.Context, n database.Notification) (Notification, error) {
+	actor, err := cfg.dbQueries.GetUserByID(ctx, n.ActorID)
+	if err != nil {
+		return Notification{}, err
+	}
+
+	notification := Notification{
+		ID:        n.ID,
+		CreatedAt: n.CreatedAt,
+		Kind:      n.Kind,
+		Actor:     mapProfile(actor),
+	}
+	if n.ChirpID.Valid {
+		notification.ChirpID = &n.ChirpID.UUID
+	}
+	return notification, nil
+}
+
+// GetNotifications lists the user's notifications newest first, grouped so
+// that notifications of the same kind about the same chirp on the same day
+// read as one, like "3 people followed you". Notifications from muted or
+// blocked users are left out.
+func (cfg *apiConfig) GetNotifications(w http.ResponseWriter, r *http.Request) {
+	userUUID, err := cfg.authenticate(r)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 401, res)
+		return
+	}
+
+	before, limit, err := parsePage(r, newestFirst)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 400, res)
+		return
+	}
+
+	rows, err := cfg.dbQueries.GetNotificationGroups(r.Context(), database.GetNotificationGroupsParams{
+		UserID:          userUUID,
+		BeforeCreatedAt: before.CreatedAt,
+		BeforeID:        before.ID,
+		PageSize:        limit,
+	})
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	unread, err := cfg.dbQueries.GetUnreadNotificationCount(r.Context(), userUUID)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	// The actor lists are newest first and can repeat someone who did the
+	// same thing twice, so keep the first few distinct ones.
+	groupActors := make([][]uuid.UUID, len(rows))
+	actorIDs := []uuid.UUID{}
+	for i, row := range rows {
+		seen := map[uuid.UUID]bool{}
+		for _, id := range row.ActorIds {
+			if len(groupActors[i]) == maxGroupActors {
+				break
+			}
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			groupActors[i] = append(groupActors[i], id)
+			actorIDs = append(actorIDs, id)
+		}
+	}
+	users, err := cfg.dbQueries.GetUsersByIDs(r.Context(), actorIDs)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+	profiles := map[uuid.UUID]Profile{}
+	for _, u := range users {
+		profiles[u.ID] = mapProfile(u)
+	}
+
+	page := Notifications{UnreadCount: unread, Groups: []NotificationGroup{}}
+	for i, row := range rows {
+		group := NotificationGroup{
+			ID:              row.LatestID,
+			Kind:            row.Kind,
+			CreatedAt:       row.LatestAt,
+			Actors:          []Profile{},
+			ActorsCount:     row.ActorsCount,
+			Unread:          row.UnreadCount > 0,
+			NotificationIDs: row.Ids,
+		}
+		if row.ChirpID.Valid {
+			group.ChirpID = &row.ChirpID.UUID
+		}
+		for _, id := range groupActors[i] {
+			group.Actors = append(group.Actors, profiles[id])
+		}
+		page.Groups = append(page.Groups, group)
+	}
+
+	if len(rows) == int(limit) {
+		last := rows[len(rows)-1]
+		setNextPage(w, r, pageCursor{CreatedAt: last.LatestAt, ID: last.LatestID})
+	}
+	jsr, err := json.Marshal(page)
+	if err != nil {
+		panic(err)
+	}
+	respondWithJson(w, 200, jsr)
+}
+
+// MarkNotificationsRead marks the notifications listed in the body as
+// read. To mark a group, send its notification_ids.
+func (cfg *apiConfig) MarkNotificationsRead(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		IDs []uuid.UUID `json:"ids"`
+	}
+
+	userUUID, err := cfg.authenticate(r)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 401, res)
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 400, res)
+		return
+	}
+	if len(params.IDs) == 0 || len(params.IDs) > maxMarkRead {
+		res := fmt.Sprintf(`{"error":"ids must list between 1 and %d notifications"}`, maxMarkRead)
+		formJsonResponse(w, 400, res)
+		return
+	}
+
+	err = cfg.dbQueries.MarkNotificationsRead(r.Context(), database.MarkNotificationsReadParams{
+		UserID: userUUID,
+		Ids:    params.IDs,
+	})
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) MarkAllNotificationsRead(w http.ResponseWriter, r *http.Request) {
+	userUUID, err := cfg.authenticate(r)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 401, res)
+		return
+	}
+
+	err = cfg.dbQueries.MarkAllNotificationsRead(r.Context(), userUUID)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+	w.WriteHeader(204)
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// pageCursor identifies the last item a client has seen. Lists are ordered
+// by (created_at, id), so the next page starts strictly after it. Ranked
+// lists like search results order by Rank first.
+type pageCursor struct {
+	Rank      float32   `json:"r,omitempty"`
+	CreatedAt time.Time `json:"t"`
+	ID        uuid.UUID `json:"id"`
+}
+
+// oldestFirst is where a list ordered oldest first starts.
+var oldestFirst = pageCursor{}
+
+// newestFirst is where a list ordered newest first starts.
+var newestFirst = pageCursor{
+	CreatedAt: time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC),
+	ID:        uuid.Max,
+}
+
+func (c pageCursor) String() string {
+	data, err := json.Marshal(c)
+	if err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(s string) (pageCursor, error) {
+	c := pageCursor{}
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, errors.New("invalid cursor")
+	}
+	err = json.Unmarshal(data, &c)
+	if err != nil {
+		return c, errors.New("invalid cursor")
+	}
+	return c, nil
+}
+
+// parsePage reads the cursor and limit query parameters, starting from
+// start when the client didn't send a cursor.
+func parsePage(r *http.Request, start pageCursor) (pageCursor, int32, error) {
+	cursor := start
+	if s := r.URL.Query().Get("cursor"); s != "" {
+		c, err := decodeCursor(s)
+		if err != nil {
+			return cursor, 0, err
+		}
+		cursor = c
+	}
+
+	limit := defaultPageSize
+	if s := r.URL.Query().Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > maxPageSize {
+			return cursor, 0, fmt.Errorf("limit must be between 1 and %d", maxPageSize)
+		}
+		limit = n
+	}
+	return cursor, int32(limit), nil
+}
+
+// setNextPage points the client at the page after next with a Link header,
+// so paginated lists keep a plain JSON array as their body.
+func setNextPage(w http.ResponseWriter, r *http.Request, next pageCursor) {
+	u := *r.URL
+	q := u.Query()
+	q.Set("cursor", next.String())
+	u.RawQuery = q.Encode()
+	w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next"`, u.RequestURI()))
+}
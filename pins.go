@@ -0,0 +1,197 @@
+package main
+
+import (
+	"chirpy/internal/database"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// maxPinnedChirps is how many chirps a user can pin to their profile. The
+// database enforces it with a check on pinned_chirps.position.
+const maxPinnedChirps = 3
+
+// PinChirp pins one of the caller's chirps below any they've already
+// pinned.
+func (cfg *apiConfig) PinChirp(w http.ResponseWriter, r *http.Request) {
+	userUUID, err := cfg.authenticate(r)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 401, res)
+		return
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpid"))
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 400, res)
+		return
+	}
+
+	owner, err := cfg.dbQueries.GetChirpOwner(r.Context(), chirpID)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 404, res)
+		return
+	}
+	if owner != userUUID {
+		formJsonResponse(w, 403, `{"error":"you can only pin your own chirps"}`)
+		return
+	}
+
+	_, err = cfg.dbQueries.PinChirp(r.Context(), database.PinChirpParams{
+		UserID:  userUUID,
+		ChirpID: chirpID,
+	})
+	if isCheckViolation(err) {
+		res := fmt.Sprintf(`{"error":"you can pin at most %d chirps"}`, maxPinnedChirps)
+		formJsonResponse(w, 409, res)
+		return
+	}
+	if isUniqueViolation(err) {
+		formJsonResponse(w, 409, `{"error":"another pin was added at the same time, try again"}`)
+		return
+	}
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) UnpinChirp(w http.ResponseWriter, r *http.Request) {
+	userUUID, err := cfg.authenticate(r)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 401, res)
+		return
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpid"))
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 400, res)
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	n, err := qtx.UnpinChirp(r.Context(), database.UnpinChirpParams{
+		UserID:  userUUID,
+		ChirpID: chirpID,
+	})
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+	if n == 0 {
+		formJsonResponse(w, 404, `{"error":"chirp is not pinned"}`)
+		return
+	}
+
+	err = qtx.CompactPinnedChirps(r.Context(), userUUID)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+// ReorderPins takes every chirp the caller has pinned, in the order they
+// should be shown.
+func (cfg *apiConfig) ReorderPins(w http.ResponseWriter, r *http.Request) {
+	type cred struct {
+		ChirpIDs []uuid.UUID `json:"chirp_ids"`
+	}
+
+	userUUID, err := cfg.authenticate(r)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 401, res)
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := cred{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 400, res)
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	pinned, err := qtx.GetPinnedChirpIDs(r.Context(), userUUID)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+	isPinned := map[uuid.UUID]bool{}
+	for _, id := range pinned {
+		isPinned[id] = true
+	}
+	seen := map[uuid.UUID]bool{}
+	for _, id := range params.ChirpIDs {
+		if !isPinned[id] || seen[id] {
+			formJsonResponse(w, 400, `{"error":"chirp_ids must list each pinned chirp once"}`)
+			return
+		}
+		seen[id] = true
+	}
+	if len(seen) != len(pinned) {
+		formJsonResponse(w, 400, `{"error":"chirp_ids must list each pinned chirp once"}`)
+		return
+	}
+
+	// A chirp pinned since GetPinnedChirpIDs isn't in chirp_ids, so it
+	// gets no position and the update fails.
+	err = qtx.ReorderPinnedChirps(r.Context(), database.ReorderPinnedChirpsParams{
+		ChirpIds: params.ChirpIDs,
+		UserID:   userUUID,
+	})
+	if isNotNullViolation(err) || isUniqueViolation(err) {
+		formJsonResponse(w, 409, `{"error":"your pins changed at the same time, try again"}`)
+		return
+	}
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+	w.WriteHeader(204)
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"chirpy/internal/database"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+const (
+	minPollOptions      = 2
+	maxPollOptions      = 4
+	maxPollOptionLength = 50
+	minPollDuration     = 5 * time.Minute
+	maxPollDuration     = 7 * 24 * time.Hour
+)
+
+// pollParams is the poll part of a new chirp. ExpiresIn is in seconds.
+type pollParams struct {
+	Options   []string `json:"options"`
+	ExpiresIn int      `json:"expires_in"`
+	Multiple  bool     `json:"multiple"`
+}
+
+// checkPoll tidies the options of a new poll and works out when it
+// closes.
+func checkPoll(p pollParams) ([]string, time.Duration, error) {
+	if len(p.Options) < minPollOptions || len(p.Options) > maxPollOptions {
+		return nil, 0, fmt.Errorf("a poll needs %d to %d options", minPollOptions, maxPollOptions)
+	}
+	options := []string{}
+	seen := map[string]bool{}
+	for _, o := range p.Options {
+		o = strings.TrimSpace(o)
+		if o == "" {
+			return nil, 0, errors.New("poll options can't be empty")
+		}
+		if utf8.RuneCountInString(o) > maxPollOptionLength {
+			return nil, 0, fmt.Errorf("poll options must be at most %d characters", maxPollOptionLength)
+		}
+		if seen[strings.ToLower(o)] {
+			return nil, 0, fmt.Errorf("poll option %q is listed twice", o)
+		}
+		seen[strings.ToLower(o)] = true
+		options = append(options, StripProfane(o))
+	}
+
+	duration := time.Duration(p.ExpiresIn) * time.Second
+	if duration < minPollDuration || duration > maxPollDuration {
+		return nil, 0, fmt.Errorf("expires_in must be between %d and %d seconds", int(minPollDuration.Seconds()), int(maxPollDuration.Seconds()))
+	}
+	return options, duration, nil
+}
+
+func createPoll(ctx context.Context, qtx *database.Queries, chirpID uuid.UUID, options []string, multiple bool, duration time.Duration) error {
+	err := qtx.CreatePoll(ctx, database.CreatePollParams{
+		ChirpID:  chirpID,
+		Multiple: multiple,
+		ClosesAt: time.Now().Add(duration),
+	})
+	if err != nil {
+		return err
+	}
+	for i, o := range options {
+		err = qtx.CreatePollOption(ctx, database.CreatePollOptionParams{
+			ChirpID:  chirpID,
+			Position: int32(i),
+			Title:    o,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// attachPolls fills in the polls of already mapped chirps as viewer sees
+// them. Counts stay hidden until the viewer has voted or the poll has
+// closed, so that early results don't sway anyone.
+func (cfg *apiConfig) attachPolls(ctx context.Context, chirps []Chirp, ids []uuid.UUID, index map[uuid.UUID]int, viewer uuid.UUID) error {
+	polls, err := cfg.dbQueries.GetPollsForChirps(ctx, ids)
+	if err != nil {
+		return err
+	}
+	if len(polls) == 0 {
+		return nil
+	}
+	for _, p := range polls {
+		chirps[index[p.ChirpID]].Poll = &Poll{
+			Multiple: p.Multiple,
+			ClosesAt: p.ClosesAt,
+			Closed:   !time.Now().Before(p.ClosesAt),
+			Options:  []PollOption{},
+			OwnVotes: []int32{},
+		}
+	}
+
+	if viewer != uuid.Nil {
+		votes, err := cfg.dbQueries.GetPollVotesByUser(ctx, database.GetPollVotesByUserParams{
+			ChirpIds: ids,
+			UserID:   viewer,
+		})
+		if err != nil {
+			return err
+		}
+		for _, v := range votes {
+			poll := chirps[index[v.ChirpID]].Poll
+			poll.Voted = true
+			poll.OwnVotes = append(poll.OwnVotes, v.Position)
+		}
+	}
+
+	options, err := cfg.dbQueries.GetPollOptionsForChirps(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for _, o := range options {
+		poll := chirps[index[o.ChirpID]].Poll
+		option := PollOption{Title: o.Title}
+		if poll.Voted || poll.Closed {
+			option.VotesCount = &o.VotesCount
+		}
+		poll.Options = append(poll.Options, option)
+	}
+	for _, p := range polls {
+		poll := chirps[index[p.ChirpID]].Poll
+		if poll.Voted || poll.Closed {
+			poll.VotersCount = &p.VotersCount
+		}
+	}
+	return nil
+}
+
+// withViewerPoll returns chirp with its poll as viewer sees it, for chirps
+// mapped once for many viewers with the poll as nobody in particular sees
+// it. chirp's own poll is left alone, since others share it.
+func (cfg *apiConfig) withViewerPoll(ctx context.Context, chirp Chirp, viewer uuid.UUID) (Chirp, error) {
+	if chirp.Poll == nil || viewer == uuid.Nil {
+		return chirp, nil
+	}
+	chirps := []Chirp{chirp}
+	err := cfg.attachPolls(ctx, chirps, []uuid.UUID{chirp.ID}, map[uuid.UUID]int{chirp.ID: 0}, viewer)
+	if err != nil {
+		return Chirp{}, err
+	}
+	return chirps[0], nil
+}
+
+// VotePoll casts the caller's one vote on a chirp's poll. choices are
+// option positions; a single-choice poll takes exactly one.
+func (cfg *apiConfig) VotePoll(w http.ResponseWriter, r *http.Request) {
+	userUUID, err := cfg.authenticate(r)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 401, res)
+		return
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpid"))
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 400, res)
+		return
+	}
+
+	type cred struct {
+		Choices []int32 `json:"choices"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	params := cred{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 400, res)
+		return
+	}
+
+	dbChirp, err := cfg.dbQueries.GetChirp(r.Context(), database.GetChirpParams{
+		ID:       chirpID,
+		ViewerID: userUUID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		formJsonResponse(w, 404, `{"error":"chirp not found"}`)
+		return
+	}
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	poll, err := cfg.dbQueries.GetPoll(r.Context(), chirpID)
+	if errors.Is(err, sql.ErrNoRows) {
+		formJsonResponse(w, 404, `{"error":"chirp has no poll"}`)
+		return
+	}
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+	if !time.Now().Before(poll.ClosesAt) {
+		formJsonResponse(w, 409, `{"error":"poll has closed"}`)
+		return
+	}
+
+	options, err := cfg.dbQueries.GetPollOptionsForChirps(r.Context(), []uuid.UUID{chirpID})
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+	if len(params.Choices) == 0 || (!poll.Multiple && len(params.Choices) > 1) {
+		formJsonResponse(w, 400, `{"error":"pick one option, or more if the poll allows multiple choices"}`)
+		return
+	}
+	seen := map[int32]bool{}
+	for _, c := range params.Choices {
+		if c < 0 || int(c) >= len(options) || seen[c] {
+			res := fmt.Sprintf(`{"error":"choices must be distinct option positions from 0 to %d"}`, len(options)-1)
+			formJsonResponse(w, 400, res)
+			return
+		}
+		seen[c] = true
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	n, err := qtx.CreatePollBallot(r.Context(), database.CreatePollBallotParams{
+		ChirpID: chirpID,
+		UserID:  userUUID,
+	})
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+	if n == 0 {
+		formJsonResponse(w, 409, `{"error":"you have already voted in this poll"}`)
+		return
+	}
+	for _, c := range params.Choices {
+		err = qtx.CreatePollVote(r.Context(), database.CreatePollVoteParams{
+			ChirpID:  chirpID,
+			UserID:   userUUID,
+			Position: c,
+		})
+		if err != nil {
+			res := fmt.Sprintf(`{"error":"%v"}`, err)
+			formJsonResponse(w, 500, res)
+			return
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	chirps := []Chirp{mapChirp(dbChirp)}
+	err = cfg.attachEntities(r.Context(), chirps, userUUID)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	jsr, err := json.Marshal(chirps[0])
+	if err != nil {
+		panic(err)
+	}
+	respondWithJson(w, 200, jsr)
+}
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"chirpy/internal/database"
+	"chirpy/internal/entities"
+	"chirpy/internal/httpcache"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+const (
+	maxDisplayNameLength = 50
+	maxBioLength         = 160
+	maxAvatarURLLength   = 2048
+
+	// handleReservationPeriod is how long a handle stays held for its
+	// previous owner after they change it, so nobody else can pick it up
+	// straight away and impersonate them.
+	handleReservationPeriod = 30 * 24 * time.Hour
+)
+
+var errHandleTaken = errors.New("handle already taken")
+
+// checkHandle applies the rules for claiming a handle and returns the status
+// to respond with when they fail. userID is the user claiming it, or
+// uuid.Nil for someone signing up.
+func checkHandle(ctx context.Context, q *database.Queries, handle string, userID uuid.UUID) (int, error) {
+	if !entities.ValidHandle(handle) {
+		return 400, fmt.Errorf("handle must be 1-%d letters, digits or underscores", entities.MaxHandleLength)
+	}
+	if entities.IsReservedHandle(handle) {
+		return 409, errors.New("handle is reserved")
+	}
+
+	reservation, err := q.GetHandleReservation(ctx, entities.NormalizeHandle(handle))
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 500, err
+	}
+	if reservation.UserID != userID {
+		return 409, errHandleTaken
+	}
+	return 0, nil
+}
+
+func validateAvatarURL(raw string) error {
+	if raw == "" {
+		return nil
+	}
+	if len(raw) > maxAvatarURLLength {
+		return errors.New("avatar_url is too long")
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("avatar_url must be an http or https URL")
+	}
+	return nil
+}
+
+func (cfg *apiConfig) GetProfile(w http.ResponseWriter, r *http.Request) {
+	handle := r.PathValue("handle")
+
+	dbUser, err := cfg.dbQueries.GetUserByHandle(r.Context(), handle)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 404, res)
+		return
+	}
+
+	counts, err := cfg.dbQueries.GetFollowCounts(r.Context(), dbUser.ID)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	profile := ProfileWithCounts{
+		Profile:        mapProfile(dbUser),
+		FollowersCount: counts.Followers,
+		FollowingCount: counts.Following,
+	}
+
+	jsr, err := json.Marshal(profile)
+	if err != nil {
+		panic(err)
+	}
+	respondWithJson(w, 200, jsr)
+}
+
+func (cfg *apiConfig) UpdateProfile(w http.ResponseWriter, r *http.Request) {
+	type profileUpdate struct {
+		Handle      *string `json:"handle"`
+		DisplayName *string `json:"display_name"`
+		Bio         *string `json:"bio"`
+		AvatarURL   *string `json:"avatar_url"`
+		Private     *bool   `json:"private"`
+	}
+
+	userUUID, err := cfg.authenticate(r)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 401, res)
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	update := profileUpdate{}
+	err = decoder.Decode(&update)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 400, res)
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	current, err := qtx.GetUserByID(r.Context(), userUUID)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 404, res)
+		return
+	}
+
+	params := database.UpdateUserProfileParams{
+		ID:          userUUID,
+		Handle:      current.Handle,
+		DisplayName: current.DisplayName,
+		Bio:         current.Bio,
+		AvatarUrl:   current.AvatarUrl,
+		Private:     current.Private,
+	}
+
+	if update.Handle != nil && *update.Handle != current.Handle {
+		status, err := checkHandle(r.Context(), qtx, *update.Handle, userUUID)
+		if err != nil {
+			res := fmt.Sprintf(`{"error":"%v"}`, err)
+			formJsonResponse(w, status, res)
+			return
+		}
+		params.Handle = *update.Handle
+	}
+	if update.DisplayName != nil {
+		params.DisplayName = strings.TrimSpace(*update.DisplayName)
+		if utf8.RuneCountInString(params.DisplayName) > maxDisplayNameLength {
+			res := fmt.Sprintf(`{"error":"display_name must be at most %d characters"}`, maxDisplayNameLength)
+			formJsonResponse(w, 400, res)
+			return
+		}
+	}
+	if update.Bio != nil {
+		params.Bio = strings.TrimSpace(*update.Bio)
+		if utf8.RuneCountInString(params.Bio) > maxBioLength {
+			res := fmt.Sprintf(`{"error":"bio must be at most %d characters"}`, maxBioLength)
+			formJsonResponse(w, 400, res)
+			return
+		}
+	}
+	if update.AvatarURL != nil {
+		err = validateAvatarURL(*update.AvatarURL)
+		if err != nil {
+			res := fmt.Sprintf(`{"error":"%v"}`, err)
+			formJsonResponse(w, 400, res)
+			return
+		}
+		params.AvatarUrl = *update.AvatarURL
+	}
+
+	if update.Private != nil {
+		params.Private = *update.Private
+	}
+
+	dbUser, err := qtx.UpdateUserProfile(r.Context(), params)
+	if isUniqueViolation(err) {
+		res := fmt.Sprintf(`{"error":"%v"}`, errHandleTaken)
+		formJsonResponse(w, 409, res)
+		return
+	}
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	oldHandle := entities.NormalizeHandle(current.Handle)
+	newHandle := entities.NormalizeHandle(dbUser.Handle)
+	if oldHandle != newHandle {
+		err = qtx.ReserveHandle(r.Context(), database.ReserveHandleParams{
+			Handle:    oldHandle,
+			UserID:    userUUID,
+			ExpiresAt: time.Now().Add(handleReservationPeriod),
+		})
+		if err != nil {
+			res := fmt.Sprintf(`{"error":"%v"}`, err)
+			formJsonResponse(w, 500, res)
+			return
+		}
+		err = qtx.ReleaseHandle(r.Context(), newHandle)
+		if err != nil {
+			res := fmt.Sprintf(`{"error":"%v"}`, err)
+			formJsonResponse(w, 500, res)
+			return
+		}
+	}
+
+	// Going public lets everyone in, including those still waiting.
+	if current.Private && !dbUser.Private {
+		err = qtx.ApproveAllFollowRequests(r.Context(), userUUID)
+		if err != nil {
+			res := fmt.Sprintf(`{"error":"%v"}`, err)
+			formJsonResponse(w, 500, res)
+			return
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	user := User{
+		ID:          dbUser.ID,
+		CreatedAt:   dbUser.CreatedAt,
+		UpdatedAt:   dbUser.UpdatedAt,
+		Email:       dbUser.Email,
+		Handle:      dbUser.Handle,
+		DisplayName: dbUser.DisplayName,
+		Bio:         dbUser.Bio,
+		AvatarURL:   dbUser.AvatarUrl,
+		Private:     dbUser.Private,
+	}
+
+	jsr, err := json.Marshal(user)
+	if err != nil {
+		panic(err)
+	}
+	respondWithJson(w, 200, jsr)
+}
+
+// userChirps loads a page of an author's chirps, newest first, as viewer
+// may see them. It backs both the JSON list and the author's feeds.
+func (cfg *apiConfig) userChirps(ctx context.Context, authorID, viewer uuid.UUID, before pageCursor, limit int32) ([]Chirp, error) {
+	dbChirps, err := cfg.dbQueries.GetUserChirps(ctx, database.GetUserChirpsParams{
+		AuthorID:        authorID,
+		ViewerID:        viewer,
+		BeforeCreatedAt: before.CreatedAt,
+		BeforeID:        before.ID,
+		PageSize:        limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	chirps := []Chirp{}
+	for _, c := range dbChirps {
+		chirps = append(chirps, mapChirp(c))
+	}
+	err = cfg.attachEntities(ctx, chirps, viewer)
+	if err != nil {
+		return nil, err
+	}
+	return chirps, nil
+}
+
+func (cfg *apiConfig) GetUserChirps(w http.ResponseWriter, r *http.Request) {
+	target, status, err := cfg.visibleUser(r)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, status, res)
+		return
+	}
+	viewerUUID, _ := cfg.viewer(r)
+
+	before, limit, err := parsePage(r, newestFirst)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 400, res)
+		return
+	}
+
+	chirps, err := cfg.userChirps(r.Context(), target.ID, viewerUUID, before, limit)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+	if len(chirps) == int(limit) {
+		last := chirps[len(chirps)-1]
+		setNextPage(w, r, pageCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+
+	jsr, err := json.Marshal(chirps)
+	if err != nil {
+		panic(err)
+	}
+	httpcache.Write(w, r, "application/json", jsr, time.Time{})
+}
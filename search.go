@@ -0,0 +1,164 @@
+package main
+
+import (
+	"chirpy/internal/database"
+	"chirpy/internal/search"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// Search is called on every keystroke by the mention picker, so the
+	// burst allows for fast typing.
+	searchRate  = 5
+	searchBurst = 20
+
+	defaultUserSearchLimit = 10
+	maxUserSearchLimit     = 20
+	maxUserSearchLength    = 50
+)
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+func (cfg *apiConfig) SearchChirps(w http.ResponseWriter, r *http.Request) {
+	viewerUUID, err := cfg.viewer(r)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 401, res)
+		return
+	}
+
+	query, err := search.Parse(r.URL.Query().Get("q"))
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 400, res)
+		return
+	}
+
+	start := newestFirst
+	start.Rank = math.MaxFloat32
+	after, limit, err := parsePage(r, start)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 400, res)
+		return
+	}
+
+	params := database.SearchChirpsParams{
+		Query:           query.TSQuery,
+		ViewerID:        viewerUUID,
+		BeforeRank:      after.Rank,
+		BeforeCreatedAt: after.CreatedAt,
+		BeforeID:        after.ID,
+		PageSize:        limit,
+	}
+	if query.Author != "" {
+		author, err := cfg.dbQueries.GetUserByHandle(r.Context(), query.Author)
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithJson(w, 200, []byte("[]"))
+			return
+		}
+		if err != nil {
+			res := fmt.Sprintf(`{"error":"%v"}`, err)
+			formJsonResponse(w, 500, res)
+			return
+		}
+		params.AuthorID = uuid.NullUUID{UUID: author.ID, Valid: true}
+	}
+
+	rows, err := cfg.dbQueries.SearchChirps(r.Context(), params)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	chirps := []Chirp{}
+	for _, row := range rows {
+		chirps = append(chirps, mapChirp(row.Chirp))
+	}
+	err = cfg.attachEntities(r.Context(), chirps, viewerUUID)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	if len(rows) == int(limit) {
+		last := rows[len(rows)-1]
+		setNextPage(w, r, pageCursor{Rank: last.Rank, CreatedAt: last.Chirp.CreatedAt, ID: last.Chirp.ID})
+	}
+	jsr, err := json.Marshal(chirps)
+	if err != nil {
+		panic(err)
+	}
+	respondWithJson(w, 200, jsr)
+}
+
+// SearchUsers backs handle autocomplete. Handles that start with the query
+// come first, then handles and display names that are merely similar to it.
+func (cfg *apiConfig) SearchUsers(w http.ResponseWriter, r *http.Request) {
+	viewerUUID, err := cfg.viewer(r)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 401, res)
+		return
+	}
+
+	q := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(r.URL.Query().Get("q")), "@"))
+	if q == "" || utf8.RuneCountInString(q) > maxUserSearchLength {
+		res := fmt.Sprintf(`{"error":"q must be 1-%d characters"}`, maxUserSearchLength)
+		formJsonResponse(w, 400, res)
+		return
+	}
+
+	limit := defaultUserSearchLimit
+	if s := r.URL.Query().Get("limit"); s != "" {
+		limit, err = strconv.Atoi(s)
+		if err != nil || limit < 1 || limit > maxUserSearchLimit {
+			res := fmt.Sprintf(`{"error":"limit must be between 1 and %d"}`, maxUserSearchLimit)
+			formJsonResponse(w, 400, res)
+			return
+		}
+	}
+
+	rows, err := cfg.dbQueries.SearchUsers(r.Context(), database.SearchUsersParams{
+		Prefix:   likeEscaper.Replace(q) + "%",
+		Query:    q,
+		ViewerID: viewerUUID,
+		PageSize: int32(limit),
+	})
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	profiles := []Profile{}
+	for _, row := range rows {
+		profiles = append(profiles, Profile{
+			ID:          row.ID,
+			CreatedAt:   row.CreatedAt,
+			Handle:      row.Handle,
+			DisplayName: row.DisplayName,
+			Bio:         row.Bio,
+			AvatarURL:   row.AvatarUrl,
+			Private:     row.Private,
+		})
+	}
+
+	jsr, err := json.Marshal(profiles)
+	if err != nil {
+		panic(err)
+	}
+	respondWithJson(w, 200, jsr)
+}
@@ -0,0 +1,63 @@
+-- name: BlockUser :exec
+INSERT INTO blocks (blocker_id, blocked_id, created_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+)
+ON CONFLICT DO NOTHING;
+
+-- name: UnblockUser :exec
+delete from blocks
+where blocker_id = $1 and blocked_id = $2;
+
+-- name: BlockedBetween :one
+select blocked_between(sqlc.arg(user_a), sqlc.arg(user_b))::boolean as blocked;
+
+-- name: BlockedAmong :one
+select exists (
+    select 1
+    from unnest(sqlc.arg(user_ids)::uuid[]) as a(id), unnest(sqlc.arg(user_ids)::uuid[]) as b(id)
+    where a.id < b.id and blocked_between(a.id, b.id)
+)::boolean as blocked;
+
+-- name: RemoveFollowsBetween :exec
+delete from follows
+where (follower_id = sqlc.arg(user_a) and followee_id = sqlc.arg(user_b))
+or (follower_id = sqlc.arg(user_b) and followee_id = sqlc.arg(user_a));
+
+-- name: RemoveFollowRequestsBetween :exec
+delete from follow_requests
+where (requester_id = sqlc.arg(user_a) and target_id = sqlc.arg(user_b))
+or (requester_id = sqlc.arg(user_b) and target_id = sqlc.arg(user_a));
+
+-- name: GetBlockedUsers :many
+select users.id, users.created_at, users.handle, users.display_name, users.bio, users.avatar_url, users.private, blocks.created_at as blocked_at
+from blocks
+join users on users.id = blocks.blocked_id
+where blocks.blocker_id = sqlc.arg(user_id)
+and (blocks.created_at, blocks.blocked_id) < (sqlc.arg(before_created_at)::timestamp, sqlc.arg(before_id)::uuid)
+order by blocks.created_at desc, blocks.blocked_id desc
+limit sqlc.arg(page_size);
+
+-- name: MuteUser :exec
+INSERT INTO mutes (muter_id, muted_id, created_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+)
+ON CONFLICT DO NOTHING;
+
+-- name: UnmuteUser :exec
+delete from mutes
+where muter_id = $1 and muted_id = $2;
+
+-- name: GetMutedUsers :many
+select users.id, users.created_at, users.handle, users.display_name, users.bio, users.avatar_url, users.private, mutes.created_at as muted_at
+from mutes
+join users on users.id = mutes.muted_id
+where mutes.muter_id = sqlc.arg(user_id)
+and (mutes.created_at, mutes.muted_id) < (sqlc.arg(before_created_at)::timestamp, sqlc.arg(before_id)::uuid)
+order by mutes.created_at desc, mutes.muted_id desc
+limit sqlc.arg(page_size);
\ No newline at end of file
@@ -0,0 +1,51 @@
+-- name: CreateCollection :one
+INSERT INTO collections (id, created_at, user_id, name)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1,
+    $2
+)
+RETURNING *;
+
+-- name: GetCollection :one
+select * from collections where id = $1 and user_id = $2;
+
+-- name: GetCollections :many
+select
+    collections.id,
+    collections.created_at,
+    collections.name,
+    (select count(*) from bookmarks where bookmarks.collection_id = collections.id) as bookmarks_count
+from collections
+where collections.user_id = $1
+order by lower(collections.name);
+
+-- name: RenameCollection :one
+update collections set name = $3
+where id = $1 and user_id = $2
+RETURNING *;
+
+-- name: DeleteCollection :execrows
+delete from collections where id = $1 and user_id = $2;
+
+-- name: UpsertBookmark :exec
+INSERT INTO bookmarks (user_id, chirp_id, collection_id, created_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    NOW()
+)
+ON CONFLICT (user_id, chirp_id) DO UPDATE SET collection_id = excluded.collection_id;
+
+-- name: DeleteBookmark :execrows
+delete from bookmarks where user_id = $1 and chirp_id = $2;
+
+-- name: GetBookmarks :many
+select * from bookmarks
+where user_id = sqlc.arg(user_id)
+and (sqlc.narg(collection_id)::uuid is null or collection_id = sqlc.narg(collection_id))
+and (created_at, chirp_id) < (sqlc.arg(before_created_at)::timestamp, sqlc.arg(before_id)::uuid)
+order by created_at desc, chirp_id desc
+limit sqlc.arg(page_size);
\ No newline at end of file
@@ -0,0 +1,68 @@
+-- name: CreateChirpEvent :one
+INSERT INTO chirp_events (created_at, kind, chirp_id, user_id)
+VALUES (
+    NOW(),
+    $1,
+    $2,
+    $3
+)
+RETURNING *;
+
+-- name: GetChirpEvent :one
+select * from chirp_events where id = $1;
+
+-- name: GetChirpEventsAfter :many
+select * from chirp_events
+where seq > sqlc.arg(after_seq)::bigint
+order by seq
+limit sqlc.arg(page_size);
+
+-- name: GetLatestChirpEventSeq :one
+select coalesce(max(seq), 0)::bigint as seq from chirp_events;
+
+-- name: DeleteChirpEventsBefore :exec
+delete from chirp_events where created_at < $1;
+
+-- name: InChirpStream :one
+select exists (
+    select 1 from chirps
+    where id = sqlc.arg(id)
+    and chirp_visible_to(id, sqlc.arg(viewer_id))
+    and (visibility <> 'unlisted' or user_id = sqlc.arg(viewer_id))
+    and user_id not in (select muted_id from mutes where muter_id = sqlc.arg(viewer_id))
+)::boolean as visible;
+
+-- name: WasInChirpStream :one
+select exists (
+    select 1 from chirps
+    where id = sqlc.arg(id)
+    and deleted_at is not null
+    and chirp_was_visible_to(id, sqlc.arg(viewer_id))
+    and (visibility <> 'unlisted' or user_id = sqlc.arg(viewer_id))
+    and user_id not in (select muted_id from mutes where muter_id = sqlc.arg(viewer_id))
+)::boolean as visible;
+
+-- name: InHomeTimeline :one
+select exists (
+    select 1 from chirps
+    where id = sqlc.arg(id)
+    and (
+        user_id = sqlc.arg(viewer_id)
+        or user_id in (select followee_id from follows where follower_id = sqlc.arg(viewer_id))
+    )
+    and chirp_visible_to(id, sqlc.arg(viewer_id))
+    and user_id not in (select muted_id from mutes where muter_id = sqlc.arg(viewer_id))
+)::boolean as visible;
+
+-- name: WasInHomeTimeline :one
+select exists (
+    select 1 from chirps
+    where id = sqlc.arg(id)
+    and deleted_at is not null
+    and (
+        user_id = sqlc.arg(viewer_id)
+        or user_id in (select followee_id from follows where follower_id = sqlc.arg(viewer_id))
+    )
+    and chirp_was_visible_to(id, sqlc.arg(viewer_id))
+    and user_id not in (select muted_id from mutes where muter_id = sqlc.arg(viewer_id))
+)::boolean as visible;
\ No newline at end of file
@@ -1,22 +1,114 @@
 -- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, visibility, content_warning)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3,
+    $4
 )
 RETURNING *;
 
 -- name: GetChirps :many
-select * from chirps;
+select * from chirps
+where chirp_visible_to(id, sqlc.arg(viewer_id))
+and (visibility <> 'unlisted' or user_id = sqlc.arg(viewer_id))
+and user_id not in (select muted_id from mutes where muter_id = sqlc.arg(viewer_id))
+and (
+    sqlc.narg(author_id)::uuid is null
+    or (
+        user_id = sqlc.narg(author_id)
+        and id not in (select chirp_id from pinned_chirps where user_id = sqlc.narg(author_id))
+    )
+)
+and (created_at, id) > (sqlc.arg(after_created_at)::timestamp, sqlc.arg(after_id)::uuid)
+order by created_at, id
+limit sqlc.arg(page_size);
+
+-- name: GetChirpsByHashtag :many
+select * from chirps
+where id in (select chirp_id from chirp_hashtags where tag = sqlc.arg(tag))
+and chirp_visible_to(id, sqlc.arg(viewer_id))
+and (visibility <> 'unlisted' or user_id = sqlc.arg(viewer_id))
+and user_id not in (select muted_id from mutes where muter_id = sqlc.arg(viewer_id))
+and (created_at, id) < (sqlc.arg(before_created_at)::timestamp, sqlc.arg(before_id)::uuid)
+order by created_at desc, id desc
+limit sqlc.arg(page_size);
+
+-- name: GetHashtagFeedVersions :many
+select chirps.id, chirps.updated_at, users.handle from chirps
+join users on users.id = chirps.user_id
+where chirps.id in (select chirp_id from chirp_hashtags where tag = sqlc.arg(tag))
+and chirp_visible_to(chirps.id, sqlc.arg(viewer_id))
+and (chirps.visibility <> 'unlisted' or chirps.user_id = sqlc.arg(viewer_id))
+and chirps.user_id not in (select muted_id from mutes where muter_id = sqlc.arg(viewer_id))
+order by chirps.created_at desc, chirps.id desc
+limit sqlc.arg(page_size);
 
 -- name: GetChirp :one
-select * from chirps where id = $1;
+select * from chirps
+where id = sqlc.arg(id) and chirp_visible_to(id, sqlc.arg(viewer_id));
+
+-- name: GetChirpsByIDs :many
+select * from chirps
+where id = ANY(sqlc.arg(ids)::uuid[]) and chirp_visible_to(id, sqlc.arg(viewer_id));
 
 -- name: GetChirpOwner :one
-select user_id from chirps where id = $1;
+select user_id from chirps where id = $1 and deleted_at is null;
+
+-- name: SoftDeleteChirp :execrows
+update chirps set deleted_at = NOW(), deleted_by = sqlc.arg(deleted_by)
+where id = sqlc.arg(id) and deleted_at is null;
+
+-- name: GetDeletedChirp :one
+select * from chirps where id = $1 and deleted_at is not null;
+
+-- name: RestoreChirp :one
+update chirps set deleted_at = null, deleted_by = null
+where id = $1 and deleted_at is not null
+RETURNING *;
+
+-- name: PurgeDeletedChirps :execrows
+delete from chirps where deleted_at < $1;
+
+-- name: GetHomeTimeline :many
+select * from chirps
+where (
+    user_id = sqlc.arg(viewer_id)
+    or user_id in (select followee_id from follows where follower_id = sqlc.arg(viewer_id))
+)
+and chirp_visible_to(id, sqlc.arg(viewer_id))
+and user_id not in (select muted_id from mutes where muter_id = sqlc.arg(viewer_id))
+and (created_at, id) < (sqlc.arg(before_created_at)::timestamp, sqlc.arg(before_id)::uuid)
+order by created_at desc, id desc
+limit sqlc.arg(page_size);
+
+-- name: GetUserChirps :many
+select * from chirps
+where user_id = sqlc.arg(author_id)
+and chirp_visible_to(id, sqlc.arg(viewer_id))
+and (created_at, id) < (sqlc.arg(before_created_at)::timestamp, sqlc.arg(before_id)::uuid)
+order by created_at desc, id desc
+limit sqlc.arg(page_size);
+
+-- name: GetUserFeedVersions :many
+select id, updated_at from chirps
+where user_id = sqlc.arg(author_id)
+and chirp_visible_to(id, sqlc.arg(viewer_id))
+order by created_at desc, id desc
+limit sqlc.arg(page_size);
 
--- name: DeleteChirp :exec
-delete from chirps where id = $1;
\ No newline at end of file
+-- name: SearchChirps :many
+select sqlc.embed(chirps), ts_rank(search, to_tsquery('english', sqlc.arg(query)))::real as rank
+from chirps
+where search @@ to_tsquery('english', sqlc.arg(query))
+and (sqlc.narg(author_id)::uuid is null or user_id = sqlc.narg(author_id))
+and chirp_visible_to(id, sqlc.arg(viewer_id))
+and (visibility <> 'unlisted' or user_id = sqlc.arg(viewer_id))
+and user_id not in (select muted_id from mutes where muter_id = sqlc.arg(viewer_id))
+and (ts_rank(search, to_tsquery('english', sqlc.arg(query)))::real, created_at, id)
+    < (sqlc.arg(before_rank)::real, sqlc.arg(before_created_at)::timestamp, sqlc.arg(before_id)::uuid)
+order by rank desc, created_at desc, id desc
+limit sqlc.arg(page_size);
\ No newline at end of file
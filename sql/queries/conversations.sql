@@ -0,0 +1,84 @@
+-- name: CreateConversation :one
+INSERT INTO conversations (id, created_at, updated_at, direct_key)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    $1
+)
+RETURNING *;
+
+-- name: AddConversationMember :exec
+INSERT INTO conversation_members (conversation_id, user_id, joined_at, last_read_at)
+VALUES (
+    $1,
+    $2,
+    NOW(),
+    NOW()
+);
+
+-- name: GetDirectConversation :one
+select * from conversations where direct_key = $1;
+
+-- name: GetConversationForMember :one
+select conversations.* from conversations
+join conversation_members on conversation_members.conversation_id = conversations.id
+where conversations.id = sqlc.arg(id) and conversation_members.user_id = sqlc.arg(user_id);
+
+-- name: GetConversations :many
+select
+    conversations.id,
+    conversations.created_at,
+    conversations.updated_at,
+    (
+        select count(*) from messages
+        where messages.conversation_id = conversations.id
+        and messages.sender_id <> sqlc.arg(user_id)
+        and messages.created_at > conversation_members.last_read_at
+    ) as unread_count
+from conversations
+join conversation_members on conversation_members.conversation_id = conversations.id
+where conversation_members.user_id = sqlc.arg(user_id)
+and (conversations.updated_at, conversations.id) < (sqlc.arg(before_updated_at)::timestamp, sqlc.arg(before_id)::uuid)
+order by conversations.updated_at desc, conversations.id desc
+limit sqlc.arg(page_size);
+
+-- name: GetConversationMembers :many
+select conversation_members.conversation_id, conversation_members.last_read_at, sqlc.embed(users)
+from conversation_members
+join users on users.id = conversation_members.user_id
+where conversation_members.conversation_id = ANY(sqlc.arg(conversation_ids)::uuid[])
+order by conversation_members.conversation_id, conversation_members.joined_at, users.id;
+
+-- name: ConversationHasBlock :one
+select exists (
+    select 1 from conversation_members
+    where conversation_id = sqlc.arg(conversation_id)
+    and user_id <> sqlc.arg(user_id)
+    and blocked_between(user_id, sqlc.arg(user_id))
+) as blocked;
+
+-- name: TouchConversation :exec
+update conversations set updated_at = NOW() where id = $1;
+
+-- name: MarkConversationRead :exec
+update conversation_members set last_read_at = NOW()
+where conversation_id = $1 and user_id = $2;
+
+-- name: CreateMessage :one
+INSERT INTO messages (id, created_at, conversation_id, sender_id, body)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1,
+    $2,
+    $3
+)
+RETURNING *;
+
+-- name: GetMessages :many
+select * from messages
+where conversation_id = sqlc.arg(conversation_id)
+and (created_at, id) < (sqlc.arg(before_created_at)::timestamp, sqlc.arg(before_id)::uuid)
+order by created_at desc, id desc
+limit sqlc.arg(page_size);
\ No newline at end of file
@@ -0,0 +1,53 @@
+-- name: CreateDraft :one
+INSERT INTO drafts (id, created_at, updated_at, user_id, body, visibility, content_warning, publish_at)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4,
+    $5
+)
+RETURNING *;
+
+-- name: GetDraft :one
+select * from drafts where id = $1 and user_id = $2;
+
+-- name: GetDrafts :many
+select * from drafts
+where user_id = sqlc.arg(user_id)
+and (sqlc.narg(scheduled)::boolean is null or (publish_at is not null) = sqlc.narg(scheduled))
+and (created_at, id) < (sqlc.arg(before_created_at)::timestamp, sqlc.arg(before_id)::uuid)
+order by created_at desc, id desc
+limit sqlc.arg(page_size);
+
+-- name: UpdateDraft :one
+update drafts
+set body = $3, visibility = $4, content_warning = $5, publish_at = $6, last_error = '', attempts = 0, retry_at = null, updated_at = NOW()
+where id = $1 and user_id = $2
+RETURNING *;
+
+-- name: DeleteDraft :execrows
+delete from drafts where id = $1 and user_id = $2;
+
+-- name: LockDraft :one
+select * from drafts where id = $1 and user_id = $2
+for update;
+
+-- name: ClaimDueDraft :one
+select * from drafts
+where publish_at <= $1
+and (retry_at is null or retry_at <= $1)
+order by coalesce(retry_at, publish_at)
+limit 1
+for update skip locked;
+
+-- name: RetryDraft :exec
+update drafts set attempts = attempts + 1, retry_at = $2, last_error = $3
+where id = $1;
+
+-- name: UnscheduleDraft :exec
+update drafts set publish_at = null, last_error = $2, attempts = 0, retry_at = null, updated_at = NOW()
+where id = $1;
\ No newline at end of file
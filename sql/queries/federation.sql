@@ -0,0 +1,120 @@
+-- name: CreateActorKey :exec
+INSERT INTO actor_keys (user_id, created_at, public_key_pem, private_key_pem)
+VALUES (
+    $1,
+    NOW(),
+    $2,
+    $3
+)
+ON CONFLICT DO NOTHING;
+
+-- name: GetActorKey :one
+select * from actor_keys where user_id = $1;
+
+-- name: UpsertRemoteActor :one
+INSERT INTO remote_actors (id, uri, handle, inbox, shared_inbox, public_key_id, public_key_pem, fetched_at)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    NOW()
+)
+ON CONFLICT (uri) DO UPDATE
+set handle = excluded.handle, inbox = excluded.inbox, shared_inbox = excluded.shared_inbox,
+    public_key_id = excluded.public_key_id, public_key_pem = excluded.public_key_pem, fetched_at = NOW()
+RETURNING *;
+
+-- name: GetRemoteActorByHandle :one
+select * from remote_actors where lower(handle) = lower($1)
+order by fetched_at desc
+limit 1;
+
+-- name: GetRemoteActorByKeyID :one
+select * from remote_actors where public_key_id = $1;
+
+-- name: DeleteRemoteActor :execrows
+delete from remote_actors where uri = $1;
+
+-- name: CreateRemoteFollower :exec
+INSERT INTO remote_followers (user_id, actor_id, follow_uri, created_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    NOW()
+)
+ON CONFLICT (user_id, actor_id) DO UPDATE
+set follow_uri = excluded.follow_uri;
+
+-- name: DeleteRemoteFollower :execrows
+delete from remote_followers where actor_id = $1 and follow_uri = $2;
+
+-- name: CountRemoteFollowers :one
+select count(*) from remote_followers where user_id = $1;
+
+-- name: GetFollowerInboxes :many
+select distinct coalesce(nullif(remote_actors.shared_inbox, ''), remote_actors.inbox)::text as inbox
+from remote_followers
+join remote_actors on remote_actors.id = remote_followers.actor_id
+where remote_followers.user_id = $1;
+
+-- name: CreateRemoteFollow :exec
+INSERT INTO remote_follows (user_id, actor_id, follow_uri, created_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    NOW()
+)
+ON CONFLICT (user_id, actor_id) DO UPDATE
+set follow_uri = excluded.follow_uri, accepted = false, created_at = NOW();
+
+-- name: AcceptRemoteFollow :execrows
+update remote_follows set accepted = true where actor_id = $1 and follow_uri = $2;
+
+-- name: RejectRemoteFollow :execrows
+delete from remote_follows where actor_id = $1 and follow_uri = $2;
+
+-- name: DeleteRemoteFollow :one
+delete from remote_follows where user_id = $1 and actor_id = $2
+RETURNING follow_uri;
+
+-- name: GetRemoteFollows :many
+select remote_actors.uri, remote_actors.handle, remote_follows.accepted, remote_follows.created_at
+from remote_follows
+join remote_actors on remote_actors.id = remote_follows.actor_id
+where remote_follows.user_id = $1
+order by remote_follows.created_at desc;
+
+-- name: EnqueueDelivery :exec
+INSERT INTO deliveries (id, created_at, user_id, inbox, activity, next_attempt_at)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    NOW()
+);
+
+-- name: ClaimDelivery :one
+update deliveries
+set attempts = attempts + 1, next_attempt_at = sqlc.arg(lease_until)
+where id = (
+    select id from deliveries
+    where next_attempt_at <= sqlc.arg(now)
+    order by next_attempt_at
+    limit 1
+    for update skip locked
+)
+RETURNING *;
+
+-- name: RetryDelivery :exec
+update deliveries set next_attempt_at = $2, last_error = $3 where id = $1;
+
+-- name: DeleteDelivery :exec
+delete from deliveries where id = $1;
\ No newline at end of file
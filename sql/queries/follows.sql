@@ -0,0 +1,74 @@
+-- name: FollowUser :execrows
+INSERT INTO follows (follower_id, followee_id, created_at)
+select sqlc.arg(follower_id)::uuid, sqlc.arg(followee_id)::uuid, NOW()
+where not blocked_between(sqlc.arg(follower_id), sqlc.arg(followee_id))
+ON CONFLICT DO NOTHING;
+
+-- name: UnfollowUser :exec
+delete from follows
+where follower_id = $1 and followee_id = $2;
+
+-- name: GetFollowers :many
+select users.id, users.created_at, users.handle, users.display_name, users.bio, users.avatar_url, users.private, follows.created_at as followed_at
+from follows
+join users on users.id = follows.follower_id
+where follows.followee_id = sqlc.arg(user_id)
+and (follows.created_at, follows.follower_id) < (sqlc.arg(before_created_at)::timestamp, sqlc.arg(before_id)::uuid)
+order by follows.created_at desc, follows.follower_id desc
+limit sqlc.arg(page_size);
+
+-- name: GetFollowing :many
+select users.id, users.created_at, users.handle, users.display_name, users.bio, users.avatar_url, users.private, follows.created_at as followed_at
+from follows
+join users on users.id = follows.followee_id
+where follows.follower_id = sqlc.arg(user_id)
+and (follows.created_at, follows.followee_id) < (sqlc.arg(before_created_at)::timestamp, sqlc.arg(before_id)::uuid)
+order by follows.created_at desc, follows.followee_id desc
+limit sqlc.arg(page_size);
+
+-- name: GetFollowCounts :one
+select
+    (select count(*) from follows where followee_id = sqlc.arg(user_id)) as followers,
+    (select count(*) from follows where follower_id = sqlc.arg(user_id)) as following;
+
+-- name: IsFollowing :one
+select exists (
+    select 1 from follows
+    where follower_id = $1 and followee_id = $2
+) as following;
+
+-- name: CanViewAuthor :one
+select can_view_author(sqlc.arg(author_id), sqlc.arg(viewer_id))::boolean as visible;
+
+-- name: CreateFollowRequest :execrows
+INSERT INTO follow_requests (requester_id, target_id, created_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+)
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteFollowRequest :execrows
+delete from follow_requests
+where requester_id = $1 and target_id = $2;
+
+-- name: GetFollowRequests :many
+select users.id, users.created_at, users.handle, users.display_name, users.bio, users.avatar_url, users.private, follow_requests.created_at as requested_at
+from follow_requests
+join users on users.id = follow_requests.requester_id
+where follow_requests.target_id = sqlc.arg(user_id)
+and (follow_requests.created_at, follow_requests.requester_id) < (sqlc.arg(before_created_at)::timestamp, sqlc.arg(before_id)::uuid)
+order by follow_requests.created_at desc, follow_requests.requester_id desc
+limit sqlc.arg(page_size);
+
+-- name: ApproveAllFollowRequests :exec
+with approved as (
+    delete from follow_requests
+    where target_id = $1
+    returning requester_id, target_id
+)
+INSERT INTO follows (follower_id, followee_id, created_at)
+select requester_id, target_id, NOW() from approved
+where not blocked_between(requester_id, target_id)
+ON CONFLICT DO NOTHING;
\ No newline at end of file
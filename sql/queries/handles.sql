@@ -0,0 +1,18 @@
+-- name: ReserveHandle :exec
+INSERT INTO handle_reservations (handle, user_id, created_at, expires_at)
+VALUES (
+    $1,
+    $2,
+    NOW(),
+    $3
+)
+ON CONFLICT (handle) DO UPDATE
+SET user_id = excluded.user_id, created_at = NOW(), expires_at = excluded.expires_at;
+
+-- name: GetHandleReservation :one
+select * from handle_reservations
+where handle = $1 and expires_at > NOW();
+
+-- name: ReleaseHandle :exec
+delete from handle_reservations
+where handle = $1;
\ No newline at end of file
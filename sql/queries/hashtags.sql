@@ -0,0 +1,15 @@
+-- name: CreateChirpHashtag :exec
+INSERT INTO chirp_hashtags (chirp_id, tag, byte_start, byte_end, char_start, char_end)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6
+);
+
+-- name: GetHashtagsForChirps :many
+select * from chirp_hashtags
+where chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[])
+order by chirp_id, byte_start;
\ No newline at end of file
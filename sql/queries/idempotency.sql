@@ -0,0 +1,26 @@
+-- name: ClaimIdempotencyKey :execrows
+INSERT INTO idempotency_keys (scope, key, created_at, fingerprint)
+VALUES (
+    sqlc.arg(scope),
+    sqlc.arg(key),
+    NOW(),
+    sqlc.arg(fingerprint)
+)
+ON CONFLICT (scope, key) DO UPDATE
+set created_at = NOW(), fingerprint = excluded.fingerprint, status = null, headers = '{}', body = null
+where idempotency_keys.created_at < sqlc.arg(expired_before)
+or (idempotency_keys.status is null and idempotency_keys.created_at < sqlc.arg(abandoned_before));
+
+-- name: GetIdempotencyKey :one
+select * from idempotency_keys where scope = $1 and key = $2;
+
+-- name: SaveIdempotentResponse :exec
+update idempotency_keys
+set status = $3, headers = $4, body = $5
+where scope = $1 and key = $2;
+
+-- name: DeleteIdempotencyKey :exec
+delete from idempotency_keys where scope = $1 and key = $2;
+
+-- name: PurgeIdempotencyKeys :execrows
+delete from idempotency_keys where created_at < $1;
\ No newline at end of file
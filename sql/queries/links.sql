@@ -0,0 +1,43 @@
+-- name: CreateLinkPreview :exec
+INSERT INTO link_previews (url, created_at)
+VALUES (
+    $1,
+    NOW()
+)
+ON CONFLICT DO NOTHING;
+
+-- name: CreateChirpLink :exec
+INSERT INTO chirp_links (chirp_id, url, byte_start, byte_end, char_start, char_end)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6
+);
+
+-- name: GetLinksForChirps :many
+select chirp_links.*, link_previews.fetched_at, link_previews.title, link_previews.description, link_previews.image_url, link_previews.failed
+from chirp_links
+join link_previews on link_previews.url = chirp_links.url
+where chirp_links.chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[])
+order by chirp_links.chirp_id, chirp_links.byte_start;
+
+-- name: ClaimLinkPreviews :many
+update link_previews
+set claimed_at = NOW()
+where url in (
+    select url from link_previews
+    where fetched_at is null
+    and (claimed_at is null or claimed_at < NOW() - interval '5 minutes')
+    order by created_at
+    limit sqlc.arg(batch_size)
+    for update skip locked
+)
+RETURNING url;
+
+-- name: SaveLinkPreview :exec
+update link_previews
+set title = $2, description = $3, image_url = $4, failed = $5, fetched_at = NOW()
+where url = $1;
\ No newline at end of file
@@ -0,0 +1,68 @@
+-- name: CreateList :one
+INSERT INTO lists (id, created_at, updated_at, owner_id, name, description, private)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4
+)
+RETURNING *;
+
+-- name: GetList :one
+select * from lists where id = $1;
+
+-- name: LockList :one
+select * from lists where id = $1 for update;
+
+-- name: GetLists :many
+select lists.*,
+    (select count(*) from list_members where list_members.list_id = lists.id) as members_count
+from lists
+where owner_id = $1
+order by lower(name), id;
+
+-- name: UpdateList :one
+update lists
+set name = $3, description = $4, private = $5, updated_at = NOW()
+where id = $1 and owner_id = $2
+RETURNING *;
+
+-- name: DeleteList :execrows
+delete from lists where id = $1 and owner_id = $2;
+
+-- name: AddListMember :exec
+INSERT INTO list_members (list_id, user_id, created_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+)
+ON CONFLICT DO NOTHING;
+
+-- name: RemoveListMember :execrows
+delete from list_members where list_id = $1 and user_id = $2;
+
+-- name: CountListMembers :one
+select count(*) from list_members where list_id = $1;
+
+-- name: GetListMembers :many
+select users.id, users.created_at, users.handle, users.display_name, users.bio, users.avatar_url, users.private, list_members.created_at as added_at
+from list_members
+join users on users.id = list_members.user_id
+where list_members.list_id = sqlc.arg(list_id)
+and not blocked_between(users.id, sqlc.arg(viewer_id))
+and (list_members.created_at, list_members.user_id) < (sqlc.arg(before_created_at)::timestamp, sqlc.arg(before_id)::uuid)
+order by list_members.created_at desc, list_members.user_id desc
+limit sqlc.arg(page_size);
+
+-- name: GetListTimeline :many
+select * from chirps
+where user_id in (select user_id from list_members where list_id = sqlc.arg(list_id))
+and chirp_visible_to(id, sqlc.arg(viewer_id))
+and user_id not in (select muted_id from mutes where muter_id = sqlc.arg(viewer_id))
+and (created_at, id) < (sqlc.arg(before_created_at)::timestamp, sqlc.arg(before_id)::uuid)
+order by created_at desc, id desc
+limit sqlc.arg(page_size);
\ No newline at end of file
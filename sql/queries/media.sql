@@ -0,0 +1,33 @@
+-- name: CreateMedia :one
+INSERT INTO media (id, created_at, user_id, content_type, width, height, thumbnail_content_type)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4,
+    $5
+)
+RETURNING *;
+
+-- name: GetMedia :one
+select * from media where id = $1;
+
+-- name: AttachMedia :execrows
+update media set chirp_id = sqlc.arg(chirp_id), position = sqlc.arg(position)
+where id = sqlc.arg(id) and user_id = sqlc.arg(user_id) and chirp_id is null;
+
+-- name: GetMediaForChirps :many
+select * from media
+where chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[])
+order by chirp_id, position;
+
+-- name: GetUnattachedMedia :many
+select * from media
+where chirp_id is null and created_at < $1
+order by created_at
+limit $2;
+
+-- name: DeleteMedia :execrows
+delete from media where id = $1 and chirp_id is null;
\ No newline at end of file
@@ -0,0 +1,16 @@
+-- name: CreateChirpMention :exec
+INSERT INTO chirp_mentions (chirp_id, user_id, handle, byte_start, byte_end, char_start, char_end)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7
+);
+
+-- name: GetMentionsForChirps :many
+select * from chirp_mentions
+where chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[])
+order by chirp_id, byte_start;
\ No newline at end of file
@@ -0,0 +1,13 @@
+-- name: IsModerator :one
+select exists (select 1 from moderators where user_id = $1);
+
+-- name: CreateModerationAction :exec
+INSERT INTO moderation_actions (id, created_at, moderator_id, action, chirp_id, author_id)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4
+);
\ No newline at end of file
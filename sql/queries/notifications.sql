@@ -0,0 +1,53 @@
+-- name: CreateNotification :one
+INSERT INTO notifications (id, created_at, user_id, actor_id, kind, chirp_id)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4
+)
+RETURNING *;
+
+-- name: GetNotification :one
+select * from notifications where id = $1;
+
+-- name: GetNotificationGroups :many
+with groups as (
+    select
+        kind,
+        chirp_id,
+        date_trunc('day', created_at)::timestamp as day,
+        max(created_at)::timestamp as latest_at,
+        (array_agg(id order by created_at desc, id desc))[1]::uuid as latest_id,
+        array_agg(actor_id order by created_at desc, id desc)::uuid[] as actor_ids,
+        count(distinct actor_id) as actors_count,
+        count(*) filter (where read_at is null) as unread_count,
+        array_agg(id order by created_at desc, id desc)::uuid[] as ids
+    from notifications
+    where user_id = sqlc.arg(user_id)
+    and not blocked_between(user_id, actor_id)
+    and actor_id not in (select muted_id from mutes where muter_id = sqlc.arg(user_id))
+    and (chirp_id is null or chirp_id not in (select id from chirps where deleted_at is not null))
+    group by kind, chirp_id, date_trunc('day', created_at)
+)
+select * from groups
+where (latest_at, latest_id) < (sqlc.arg(before_created_at)::timestamp, sqlc.arg(before_id)::uuid)
+order by latest_at desc, latest_id desc
+limit sqlc.arg(page_size);
+
+-- name: GetUnreadNotificationCount :one
+select count(*) from notifications
+where user_id = sqlc.arg(user_id) and read_at is null
+and not blocked_between(user_id, actor_id)
+and actor_id not in (select muted_id from mutes where muter_id = sqlc.arg(user_id))
+and (chirp_id is null or chirp_id not in (select id from chirps where deleted_at is not null));
+
+-- name: MarkNotificationsRead :exec
+update notifications set read_at = NOW()
+where user_id = sqlc.arg(user_id) and id = ANY(sqlc.arg(ids)::uuid[]) and read_at is null;
+
+-- name: MarkAllNotificationsRead :exec
+update notifications set read_at = NOW()
+where user_id = $1 and read_at is null;
\ No newline at end of file
@@ -0,0 +1,42 @@
+-- name: PinChirp :execrows
+INSERT INTO pinned_chirps (user_id, chirp_id, position, created_at)
+VALUES (
+    sqlc.arg(user_id),
+    sqlc.arg(chirp_id),
+    (select count(*) from pinned_chirps where user_id = sqlc.arg(user_id)),
+    NOW()
+)
+ON CONFLICT (user_id, chirp_id) DO NOTHING;
+
+-- name: UnpinChirp :execrows
+delete from pinned_chirps where user_id = $1 and chirp_id = $2;
+
+-- name: CompactPinnedChirps :exec
+update pinned_chirps p set position = r.n - 1
+from (
+    select chirp_id, row_number() over (order by position) as n
+    from pinned_chirps where user_id = sqlc.arg(user_id)
+) r
+where p.user_id = sqlc.arg(user_id) and p.chirp_id = r.chirp_id;
+
+-- name: ReorderPinnedChirps :exec
+update pinned_chirps set position = array_position(sqlc.arg(chirp_ids)::uuid[], chirp_id) - 1
+where user_id = sqlc.arg(user_id);
+
+-- name: GetPinnedChirpIDs :many
+select chirp_id from pinned_chirps
+where user_id = $1
+order by position;
+
+-- name: GetPinnedChirps :many
+select chirps.* from chirps
+join pinned_chirps on pinned_chirps.chirp_id = chirps.id
+where pinned_chirps.user_id = sqlc.arg(author_id)
+and chirp_visible_to(chirps.id, sqlc.arg(viewer_id))
+and (chirps.visibility <> 'unlisted' or chirps.user_id = sqlc.arg(viewer_id))
+and chirps.user_id not in (select muted_id from mutes where muter_id = sqlc.arg(viewer_id))
+order by pinned_chirps.position;
+
+-- name: GetPinnedForChirps :many
+select chirp_id from pinned_chirps
+where chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[]);
\ No newline at end of file
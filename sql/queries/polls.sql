@@ -0,0 +1,39 @@
+-- name: CreatePoll :exec
+INSERT INTO polls (chirp_id, multiple, closes_at)
+VALUES ($1, $2, $3);
+
+-- name: CreatePollOption :exec
+INSERT INTO poll_options (chirp_id, position, title)
+VALUES ($1, $2, $3);
+
+-- name: GetPoll :one
+select * from polls where chirp_id = $1;
+
+-- name: GetPollsForChirps :many
+select polls.chirp_id, polls.multiple, polls.closes_at, count(poll_ballots.user_id) as voters_count
+from polls
+left join poll_ballots on poll_ballots.chirp_id = polls.chirp_id
+where polls.chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[])
+group by polls.chirp_id;
+
+-- name: GetPollOptionsForChirps :many
+select poll_options.chirp_id, poll_options.position, poll_options.title, count(poll_votes.user_id) as votes_count
+from poll_options
+left join poll_votes on poll_votes.chirp_id = poll_options.chirp_id and poll_votes.position = poll_options.position
+where poll_options.chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[])
+group by poll_options.chirp_id, poll_options.position
+order by poll_options.chirp_id, poll_options.position;
+
+-- name: GetPollVotesByUser :many
+select chirp_id, position from poll_votes
+where chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[]) and user_id = sqlc.arg(user_id)
+order by chirp_id, position;
+
+-- name: CreatePollBallot :execrows
+INSERT INTO poll_ballots (chirp_id, user_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT DO NOTHING;
+
+-- name: CreatePollVote :exec
+INSERT INTO poll_votes (chirp_id, user_id, position)
+VALUES ($1, $2, $3);
\ No newline at end of file
@@ -0,0 +1,83 @@
+-- name: ScoreHashtags :many
+select
+    h.tag,
+    count(distinct h.chirp_id) as uses,
+    sum(power(0.5::float8, extract(epoch from NOW() - c.created_at)::float8 / sqlc.arg(half_life_seconds)::float8))::float8 as score
+from chirp_hashtags h
+join chirps c on c.id = h.chirp_id
+join users u on u.id = c.user_id
+where c.created_at > NOW() - make_interval(secs => sqlc.arg(window_seconds)::float8)
+and c.visibility = 'public'
+and c.deleted_at is null
+and not u.private
+group by h.tag
+order by score desc, h.tag
+limit sqlc.arg(max_results);
+
+-- name: ScoreChirps :many
+select
+    b.chirp_id,
+    sum(power(0.5::float8, extract(epoch from NOW() - b.created_at)::float8 / sqlc.arg(half_life_seconds)::float8))::float8 as score
+from poll_ballots b
+join chirps c on c.id = b.chirp_id
+join users u on u.id = c.user_id
+where b.created_at > NOW() - make_interval(secs => sqlc.arg(window_seconds)::float8)
+and b.user_id <> c.user_id
+and c.visibility = 'public'
+and c.deleted_at is null
+and not u.private
+group by b.chirp_id
+order by score desc, b.chirp_id
+limit sqlc.arg(max_results);
+
+-- name: CreateTrendSnapshot :one
+INSERT INTO trend_snapshots (id, window_name, generated_at)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    NOW()
+)
+RETURNING *;
+
+-- name: CreateTrendingHashtag :exec
+INSERT INTO trending_hashtags (snapshot_id, position, tag, uses, score)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5
+);
+
+-- name: CreateTrendingChirp :exec
+INSERT INTO trending_chirps (snapshot_id, position, chirp_id, score)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4
+);
+
+-- name: DeleteOlderTrendSnapshots :exec
+delete from trend_snapshots
+where window_name = $1 and generated_at < $2;
+
+-- name: GetLatestTrendSnapshot :one
+select * from trend_snapshots
+where window_name = $1
+order by generated_at desc
+limit 1;
+
+-- name: GetTrendingHashtags :many
+select * from trending_hashtags
+where snapshot_id = $1
+order by position;
+
+-- name: GetTrendingChirps :many
+select sqlc.embed(chirps), trending_chirps.score
+from trending_chirps
+join chirps on chirps.id = trending_chirps.chirp_id
+where trending_chirps.snapshot_id = sqlc.arg(snapshot_id)
+and chirp_visible_to(chirps.id, sqlc.arg(viewer_id))
+and chirps.user_id not in (select muted_id from mutes where muter_id = sqlc.arg(viewer_id))
+order by trending_chirps.position;
\ No newline at end of file
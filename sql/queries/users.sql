@@ -1,11 +1,12 @@
 -- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, email, hashed_password)
+INSERT INTO users (id, created_at, updated_at, email, hashed_password, handle)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3
 )
 RETURNING *;
 
@@ -19,4 +20,40 @@ RETURNING id, created_at, updated_at, email;
 delete from users;
 
 -- name: GetUserByEmail :one
-select * from users where email = $1;
\ No newline at end of file
+select * from users where email = $1;
+
+-- name: GetUsersByHandles :many
+select id, handle from users
+where lower(handle) = ANY(sqlc.arg(handles)::text[])
+and not blocked_between(id, sqlc.arg(author_id));
+
+-- name: GetUserByID :one
+select * from users where id = $1;
+
+-- name: GetUsersByIDs :many
+select * from users where id = ANY(sqlc.arg(ids)::uuid[]);
+
+-- name: GetUserByHandle :one
+select * from users where lower(handle) = lower($1);
+
+-- name: UpdateUserProfile :one
+update users
+set handle = $2, display_name = $3, bio = $4, avatar_url = $5, private = $6, updated_at = NOW()
+where id = $1
+RETURNING *;
+
+-- name: SearchUsers :many
+select id, created_at, handle, display_name, bio, avatar_url, private
+from users
+where (
+    lower(handle) like sqlc.arg(prefix)
+    or lower(display_name) like sqlc.arg(prefix)
+    or lower(handle) % sqlc.arg(query)
+    or lower(display_name) % sqlc.arg(query)
+)
+and not blocked_between(id, sqlc.arg(viewer_id))
+order by
+    lower(handle) like sqlc.arg(prefix) desc,
+    greatest(similarity(lower(handle), sqlc.arg(query)), similarity(lower(display_name), sqlc.arg(query))) desc,
+    lower(handle)
+limit sqlc.arg(page_size);
\ No newline at end of file
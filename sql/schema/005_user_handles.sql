@@ -0,0 +1,17 @@
+-- +goose Up
+alter TABLE users
+add handle text;
+
+update users
+set handle = 'user_' || substr(replace(id::text, '-', ''), 1, 10);
+
+alter table users
+alter column handle set not null;
+
+CREATE UNIQUE INDEX users_handle_key ON users (lower(handle));
+
+-- +goose Down
+DROP INDEX users_handle_key;
+
+alter table users
+drop column handle;
\ No newline at end of file
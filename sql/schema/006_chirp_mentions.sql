@@ -0,0 +1,18 @@
+-- +goose Up
+CREATE TABLE chirp_mentions (
+    chirp_id uuid not null,
+    user_id uuid not null,
+    handle text not null,
+    byte_start int not null,
+    byte_end int not null,
+    char_start int not null,
+    char_end int not null,
+    primary key (chirp_id, byte_start),
+    foreign key (chirp_id)
+    references chirps(id) on delete cascade,
+    foreign key (user_id)
+    references users(id) on delete cascade
+);
+
+-- +goose Down
+DROP TABLE chirp_mentions;
\ No newline at end of file
@@ -0,0 +1,19 @@
+-- +goose Up
+CREATE TABLE notifications (
+    id uuid not null,
+    created_at timestamp not null,
+    user_id uuid not null,
+    actor_id uuid not null,
+    kind text not null,
+    chirp_id uuid,
+    primary key (id),
+    foreign key (user_id)
+    references users(id) on delete cascade,
+    foreign key (actor_id)
+    references users(id) on delete cascade,
+    foreign key (chirp_id)
+    references chirps(id) on delete cascade
+);
+
+-- +goose Down
+DROP TABLE notifications;
\ No newline at end of file
@@ -0,0 +1,11 @@
+-- +goose Up
+alter TABLE users
+add display_name text not null default '',
+add bio text not null default '',
+add avatar_url text not null default '';
+
+-- +goose Down
+alter table users
+drop column display_name,
+drop column bio,
+drop column avatar_url;
\ No newline at end of file
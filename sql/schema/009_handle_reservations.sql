@@ -0,0 +1,13 @@
+-- +goose Up
+CREATE TABLE handle_reservations (
+    handle text not null,
+    user_id uuid not null,
+    created_at timestamp not null,
+    expires_at timestamp not null,
+    primary key (handle),
+    foreign key (user_id)
+    references users(id) on delete cascade
+);
+
+-- +goose Down
+DROP TABLE handle_reservations;
\ No newline at end of file
@@ -0,0 +1,21 @@
+-- +goose Up
+CREATE TABLE follows (
+    follower_id uuid not null,
+    followee_id uuid not null,
+    created_at timestamp not null,
+    primary key (follower_id, followee_id),
+    foreign key (follower_id)
+    references users(id) on delete cascade,
+    foreign key (followee_id)
+    references users(id) on delete cascade,
+    check (follower_id <> followee_id)
+);
+
+CREATE INDEX follows_followee_idx ON follows (followee_id, created_at);
+
+CREATE INDEX chirps_user_created_idx ON chirps (user_id, created_at);
+
+-- +goose Down
+DROP INDEX chirps_user_created_idx;
+
+DROP TABLE follows;
\ No newline at end of file
@@ -0,0 +1,44 @@
+-- +goose Up
+CREATE TABLE blocks (
+    blocker_id uuid not null,
+    blocked_id uuid not null,
+    created_at timestamp not null,
+    primary key (blocker_id, blocked_id),
+    foreign key (blocker_id)
+    references users(id) on delete cascade,
+    foreign key (blocked_id)
+    references users(id) on delete cascade,
+    check (blocker_id <> blocked_id)
+);
+
+CREATE INDEX blocks_blocked_idx ON blocks (blocked_id);
+
+CREATE TABLE mutes (
+    muter_id uuid not null,
+    muted_id uuid not null,
+    created_at timestamp not null,
+    primary key (muter_id, muted_id),
+    foreign key (muter_id)
+    references users(id) on delete cascade,
+    foreign key (muted_id)
+    references users(id) on delete cascade,
+    check (muter_id <> muted_id)
+);
+
+-- +goose StatementBegin
+CREATE FUNCTION blocked_between(user_a uuid, user_b uuid) RETURNS boolean
+LANGUAGE sql STABLE AS $$
+    select exists (
+        select 1 from blocks
+        where (blocker_id = user_a and blocked_id = user_b)
+        or (blocker_id = user_b and blocked_id = user_a)
+    );
+$$;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP FUNCTION blocked_between(uuid, uuid);
+
+DROP TABLE mutes;
+
+DROP TABLE blocks;
\ No newline at end of file
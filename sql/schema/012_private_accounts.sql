@@ -0,0 +1,41 @@
+-- +goose Up
+alter TABLE users
+add private boolean not null default false;
+
+CREATE TABLE follow_requests (
+    requester_id uuid not null,
+    target_id uuid not null,
+    created_at timestamp not null,
+    primary key (requester_id, target_id),
+    foreign key (requester_id)
+    references users(id) on delete cascade,
+    foreign key (target_id)
+    references users(id) on delete cascade,
+    check (requester_id <> target_id)
+);
+
+CREATE INDEX follow_requests_target_idx ON follow_requests (target_id, created_at);
+
+-- +goose StatementBegin
+CREATE FUNCTION can_view_author(author_id uuid, viewer_id uuid) RETURNS boolean
+LANGUAGE sql STABLE AS $$
+    select author_id = viewer_id or (
+        not blocked_between(author_id, viewer_id)
+        and (
+            not (select private from users where id = author_id)
+            or exists (
+                select 1 from follows
+                where follower_id = viewer_id and followee_id = author_id
+            )
+        )
+    );
+$$;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP FUNCTION can_view_author(uuid, uuid);
+
+DROP TABLE follow_requests;
+
+alter table users
+drop column private;
\ No newline at end of file
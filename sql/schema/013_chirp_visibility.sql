@@ -0,0 +1,35 @@
+-- +goose Up
+alter TABLE chirps
+add visibility text not null default 'public'
+    check (visibility in ('public', 'followers', 'mentioned', 'unlisted')),
+add content_warning text not null default '';
+
+-- +goose StatementBegin
+CREATE FUNCTION chirp_visible_to(chirp uuid, viewer uuid) RETURNS boolean
+LANGUAGE sql STABLE AS $$
+    select exists (
+        select 1 from chirps c
+        where c.id = chirp
+        and can_view_author(c.user_id, viewer)
+        and (
+            c.user_id = viewer
+            or c.visibility in ('public', 'unlisted')
+            or (c.visibility = 'followers' and exists (
+                select 1 from follows f
+                where f.follower_id = viewer and f.followee_id = c.user_id
+            ))
+            or (c.visibility = 'mentioned' and exists (
+                select 1 from chirp_mentions m
+                where m.chirp_id = c.id and m.user_id = viewer
+            ))
+        )
+    );
+$$;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP FUNCTION chirp_visible_to(uuid, uuid);
+
+alter table chirps
+drop column visibility,
+drop column content_warning;
\ No newline at end of file
@@ -0,0 +1,11 @@
+-- +goose Up
+alter TABLE chirps
+add search tsvector generated always as (to_tsvector('english'::regconfig, body)) stored;
+
+CREATE INDEX chirps_search_idx ON chirps USING gin (search);
+
+-- +goose Down
+DROP INDEX chirps_search_idx;
+
+alter table chirps
+drop column search;
\ No newline at end of file
@@ -0,0 +1,17 @@
+-- +goose Up
+CREATE TABLE chirp_hashtags (
+    chirp_id uuid not null,
+    tag text not null,
+    byte_start int not null,
+    byte_end int not null,
+    char_start int not null,
+    char_end int not null,
+    primary key (chirp_id, byte_start),
+    foreign key (chirp_id)
+    references chirps(id) on delete cascade
+);
+
+CREATE INDEX chirp_hashtags_tag_idx ON chirp_hashtags (tag);
+
+-- +goose Down
+DROP TABLE chirp_hashtags;
\ No newline at end of file
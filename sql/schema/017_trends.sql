@@ -0,0 +1,25 @@
+-- +goose Up
+CREATE TABLE trend_snapshots (
+    id uuid not null,
+    window_name text not null,
+    generated_at timestamp not null,
+    primary key (id)
+);
+
+CREATE INDEX trend_snapshots_window_idx ON trend_snapshots (window_name, generated_at);
+
+CREATE TABLE trending_hashtags (
+    snapshot_id uuid not null,
+    position int not null,
+    tag text not null,
+    uses bigint not null,
+    score double precision not null,
+    primary key (snapshot_id, position),
+    foreign key (snapshot_id)
+    references trend_snapshots(id) on delete cascade
+);
+
+-- +goose Down
+DROP TABLE trending_hashtags;
+
+DROP TABLE trend_snapshots;
\ No newline at end of file
@@ -0,0 +1,32 @@
+-- +goose Up
+CREATE TABLE chirp_events (
+    id bigserial primary key,
+    created_at timestamp not null,
+    kind text not null,
+    chirp_id uuid not null,
+    user_id uuid not null,
+    foreign key (user_id)
+    references users(id) on delete cascade
+);
+
+CREATE INDEX chirp_events_created_at_idx ON chirp_events (created_at);
+
+-- +goose StatementBegin
+CREATE FUNCTION notify_chirp_event() RETURNS trigger
+LANGUAGE plpgsql AS $$
+BEGIN
+    PERFORM pg_notify('chirp_events', NEW.id::text);
+    RETURN NEW;
+END;
+$$;
+-- +goose StatementEnd
+
+CREATE TRIGGER chirp_events_notify AFTER INSERT ON chirp_events
+FOR EACH ROW EXECUTE FUNCTION notify_chirp_event();
+
+-- +goose Down
+DROP TRIGGER chirp_events_notify ON chirp_events;
+
+DROP FUNCTION notify_chirp_event();
+
+DROP TABLE chirp_events;
\ No newline at end of file
@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE FUNCTION notify_notification() RETURNS trigger
+LANGUAGE plpgsql AS $$
+BEGIN
+    PERFORM pg_notify('notifications', NEW.id::text);
+    RETURN NEW;
+END;
+$$;
+-- +goose StatementEnd
+
+CREATE TRIGGER notifications_notify AFTER INSERT ON notifications
+FOR EACH ROW EXECUTE FUNCTION notify_notification();
+
+-- +goose Down
+DROP TRIGGER notifications_notify ON notifications;
+
+DROP FUNCTION notify_notification();
\ No newline at end of file
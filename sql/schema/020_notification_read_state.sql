@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE notifications ADD COLUMN read_at timestamp;
+
+CREATE INDEX notifications_user_created_at_idx ON notifications (user_id, created_at);
+
+CREATE INDEX notifications_unread_idx ON notifications (user_id) WHERE read_at IS NULL;
+
+-- +goose Down
+DROP INDEX notifications_unread_idx;
+
+DROP INDEX notifications_user_created_at_idx;
+
+ALTER TABLE notifications DROP COLUMN read_at;
\ No newline at end of file
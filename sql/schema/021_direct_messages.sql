@@ -0,0 +1,44 @@
+-- +goose Up
+CREATE TABLE conversations (
+    id uuid not null,
+    created_at timestamp not null,
+    updated_at timestamp not null,
+    direct_key text unique,
+    primary key (id)
+);
+
+CREATE TABLE conversation_members (
+    conversation_id uuid not null,
+    user_id uuid not null,
+    joined_at timestamp not null,
+    last_read_at timestamp not null,
+    primary key (conversation_id, user_id),
+    foreign key (conversation_id)
+    references conversations(id) on delete cascade,
+    foreign key (user_id)
+    references users(id) on delete cascade
+);
+
+CREATE INDEX conversation_members_user_idx ON conversation_members (user_id);
+
+CREATE TABLE messages (
+    id uuid not null,
+    created_at timestamp not null,
+    conversation_id uuid not null,
+    sender_id uuid not null,
+    body text not null,
+    primary key (id),
+    foreign key (conversation_id)
+    references conversations(id) on delete cascade,
+    foreign key (sender_id)
+    references users(id) on delete cascade
+);
+
+CREATE INDEX messages_conversation_created_at_idx ON messages (conversation_id, created_at, id);
+
+-- +goose Down
+DROP TABLE messages;
+
+DROP TABLE conversation_members;
+
+DROP TABLE conversations;
\ No newline at end of file
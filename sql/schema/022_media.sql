@@ -0,0 +1,24 @@
+-- +goose Up
+CREATE TABLE media (
+    id uuid not null,
+    created_at timestamp not null,
+    user_id uuid not null,
+    chirp_id uuid,
+    position int not null default 0,
+    content_type text not null,
+    width int not null,
+    height int not null,
+    thumbnail_content_type text not null,
+    primary key (id),
+    foreign key (user_id)
+    references users(id) on delete cascade,
+    foreign key (chirp_id)
+    references chirps(id) on delete set null
+);
+
+CREATE INDEX media_chirp_idx ON media (chirp_id, position);
+
+CREATE INDEX media_unattached_idx ON media (created_at) WHERE chirp_id IS NULL;
+
+-- +goose Down
+DROP TABLE media;
\ No newline at end of file
@@ -0,0 +1,53 @@
+-- +goose Up
+CREATE TABLE polls (
+    chirp_id uuid not null,
+    multiple boolean not null,
+    closes_at timestamp not null,
+    primary key (chirp_id),
+    foreign key (chirp_id)
+    references chirps(id) on delete cascade
+);
+
+CREATE TABLE poll_options (
+    chirp_id uuid not null,
+    position int not null,
+    title text not null,
+    primary key (chirp_id, position),
+    foreign key (chirp_id)
+    references polls(chirp_id) on delete cascade
+);
+
+-- A ballot is one user's vote on a poll; its primary key is what stops
+-- anyone voting twice. The options it picked are in poll_votes.
+CREATE TABLE poll_ballots (
+    chirp_id uuid not null,
+    user_id uuid not null,
+    created_at timestamp not null,
+    primary key (chirp_id, user_id),
+    foreign key (chirp_id)
+    references polls(chirp_id) on delete cascade,
+    foreign key (user_id)
+    references users(id) on delete cascade
+);
+
+CREATE TABLE poll_votes (
+    chirp_id uuid not null,
+    user_id uuid not null,
+    position int not null,
+    primary key (chirp_id, user_id, position),
+    foreign key (chirp_id, user_id)
+    references poll_ballots(chirp_id, user_id) on delete cascade,
+    foreign key (chirp_id, position)
+    references poll_options(chirp_id, position) on delete cascade
+);
+
+CREATE INDEX poll_votes_option_idx ON poll_votes (chirp_id, position);
+
+-- +goose Down
+DROP TABLE poll_votes;
+
+DROP TABLE poll_ballots;
+
+DROP TABLE poll_options;
+
+DROP TABLE polls;
\ No newline at end of file
@@ -0,0 +1,25 @@
+-- +goose Up
+-- A draft with a publish_at is a scheduled chirp. Either way it is only
+-- ever visible to its author until it is published, when the row is
+-- replaced by a chirp.
+CREATE TABLE drafts (
+    id uuid not null,
+    created_at timestamp not null,
+    updated_at timestamp not null,
+    user_id uuid not null,
+    body text not null,
+    visibility text not null default 'public',
+    content_warning text not null default '',
+    publish_at timestamp,
+    last_error text not null default '',
+    primary key (id),
+    foreign key (user_id)
+    references users(id) on delete cascade
+);
+
+CREATE INDEX drafts_user_created_at_idx ON drafts (user_id, created_at);
+
+CREATE INDEX drafts_due_idx ON drafts (publish_at) WHERE publish_at IS NOT NULL;
+
+-- +goose Down
+DROP TABLE drafts;
\ No newline at end of file
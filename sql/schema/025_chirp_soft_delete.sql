@@ -0,0 +1,88 @@
+-- +goose Up
+alter TABLE chirps
+add deleted_at timestamp,
+add deleted_by uuid references users(id) on delete set null;
+
+CREATE INDEX chirps_deleted_at_idx ON chirps (deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- Moderators are granted by hand in the database.
+CREATE TABLE moderators (
+    user_id uuid not null,
+    created_at timestamp not null default NOW(),
+    primary key (user_id),
+    foreign key (user_id)
+    references users(id) on delete cascade
+);
+
+-- moderation_actions keeps what moderators did to other people's chirps
+-- after the chirps themselves are purged, so it has no foreign keys to
+-- them.
+CREATE TABLE moderation_actions (
+    id uuid not null,
+    created_at timestamp not null,
+    moderator_id uuid,
+    action text not null check (action in ('delete_chirp', 'restore_chirp')),
+    chirp_id uuid not null,
+    author_id uuid not null,
+    primary key (id),
+    foreign key (moderator_id)
+    references users(id) on delete set null
+);
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION chirp_visible_to(chirp uuid, viewer uuid) RETURNS boolean
+LANGUAGE sql STABLE AS $$
+    select exists (
+        select 1 from chirps c
+        where c.id = chirp
+        and c.deleted_at is null
+        and can_view_author(c.user_id, viewer)
+        and (
+            c.user_id = viewer
+            or c.visibility in ('public', 'unlisted')
+            or (c.visibility = 'followers' and exists (
+                select 1 from follows f
+                where f.follower_id = viewer and f.followee_id = c.user_id
+            ))
+            or (c.visibility = 'mentioned' and exists (
+                select 1 from chirp_mentions m
+                where m.chirp_id = c.id and m.user_id = viewer
+            ))
+        )
+    );
+$$;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION chirp_visible_to(chirp uuid, viewer uuid) RETURNS boolean
+LANGUAGE sql STABLE AS $$
+    select exists (
+        select 1 from chirps c
+        where c.id = chirp
+        and can_view_author(c.user_id, viewer)
+        and (
+            c.user_id = viewer
+            or c.visibility in ('public', 'unlisted')
+            or (c.visibility = 'followers' and exists (
+                select 1 from follows f
+                where f.follower_id = viewer and f.followee_id = c.user_id
+            ))
+            or (c.visibility = 'mentioned' and exists (
+                select 1 from chirp_mentions m
+                where m.chirp_id = c.id and m.user_id = viewer
+            ))
+        )
+    );
+$$;
+-- +goose StatementEnd
+
+DROP TABLE moderation_actions;
+
+DROP TABLE moderators;
+
+DROP INDEX chirps_deleted_at_idx;
+
+alter table chirps
+drop column deleted_at,
+drop column deleted_by;
\ No newline at end of file
@@ -0,0 +1,23 @@
+-- +goose Up
+alter TABLE chirps
+add unique (id, user_id);
+
+-- Pins are numbered from 0 in the order they're shown. The check on
+-- position is what caps how many chirps a user can pin; keep it in step
+-- with maxPinnedChirps.
+CREATE TABLE pinned_chirps (
+    user_id uuid not null,
+    chirp_id uuid not null,
+    position int not null check (position >= 0 and position < 3),
+    created_at timestamp not null,
+    primary key (user_id, chirp_id),
+    unique (user_id, position) deferrable initially immediate,
+    foreign key (chirp_id, user_id)
+    references chirps(id, user_id) on delete cascade
+);
+
+-- +goose Down
+DROP TABLE pinned_chirps;
+
+alter table chirps
+drop constraint chirps_id_user_id_key;
\ No newline at end of file
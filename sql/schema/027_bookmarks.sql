@@ -0,0 +1,35 @@
+-- +goose Up
+CREATE TABLE collections (
+    id uuid not null,
+    created_at timestamp not null,
+    user_id uuid not null,
+    name text not null,
+    primary key (id),
+    foreign key (user_id)
+    references users(id) on delete cascade
+);
+
+CREATE UNIQUE INDEX collections_user_name_idx ON collections (user_id, lower(name));
+
+-- chirp_id has no foreign key so that a bookmark outlives its chirp and
+-- can be listed as a tombstone.
+CREATE TABLE bookmarks (
+    user_id uuid not null,
+    chirp_id uuid not null,
+    collection_id uuid,
+    created_at timestamp not null,
+    primary key (user_id, chirp_id),
+    foreign key (user_id)
+    references users(id) on delete cascade,
+    foreign key (collection_id)
+    references collections(id) on delete set null
+);
+
+CREATE INDEX bookmarks_user_created_at_idx ON bookmarks (user_id, created_at);
+
+CREATE INDEX bookmarks_collection_idx ON bookmarks (collection_id, created_at);
+
+-- +goose Down
+DROP TABLE bookmarks;
+
+DROP TABLE collections;
\ No newline at end of file
@@ -0,0 +1,33 @@
+-- +goose Up
+CREATE TABLE lists (
+    id uuid not null,
+    created_at timestamp not null,
+    updated_at timestamp not null,
+    owner_id uuid not null,
+    name text not null,
+    description text not null default '',
+    private boolean not null default false,
+    primary key (id),
+    foreign key (owner_id)
+    references users(id) on delete cascade
+);
+
+CREATE INDEX lists_owner_idx ON lists (owner_id);
+
+CREATE TABLE list_members (
+    list_id uuid not null,
+    user_id uuid not null,
+    created_at timestamp not null,
+    primary key (list_id, user_id),
+    foreign key (list_id)
+    references lists(id) on delete cascade,
+    foreign key (user_id)
+    references users(id) on delete cascade
+);
+
+CREATE INDEX list_members_user_idx ON list_members (user_id);
+
+-- +goose Down
+DROP TABLE list_members;
+
+DROP TABLE lists;
\ No newline at end of file
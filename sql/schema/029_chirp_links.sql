@@ -0,0 +1,32 @@
+-- +goose Up
+CREATE TABLE link_previews (
+    url text not null,
+    created_at timestamp not null,
+    fetched_at timestamp,
+    title text not null default '',
+    description text not null default '',
+    image_url text not null default '',
+    failed boolean not null default false,
+    primary key (url)
+);
+
+CREATE INDEX link_previews_pending_idx ON link_previews (created_at) WHERE fetched_at IS NULL;
+
+CREATE TABLE chirp_links (
+    chirp_id uuid not null,
+    url text not null,
+    byte_start int not null,
+    byte_end int not null,
+    char_start int not null,
+    char_end int not null,
+    primary key (chirp_id, byte_start),
+    foreign key (chirp_id)
+    references chirps(id) on delete cascade,
+    foreign key (url)
+    references link_previews(url)
+);
+
+-- +goose Down
+DROP TABLE chirp_links;
+
+DROP TABLE link_previews;
\ No newline at end of file
@@ -0,0 +1,16 @@
+-- +goose Up
+CREATE TABLE idempotency_keys (
+    scope text not null,
+    key text not null,
+    created_at timestamp not null,
+    fingerprint text not null,
+    status int,
+    headers jsonb not null default '{}',
+    body bytea,
+    primary key (scope, key)
+);
+
+CREATE INDEX idempotency_keys_created_idx ON idempotency_keys (created_at);
+
+-- +goose Down
+DROP TABLE idempotency_keys;
\ No newline at end of file
@@ -0,0 +1,76 @@
+-- +goose Up
+CREATE TABLE actor_keys (
+    user_id uuid not null,
+    created_at timestamp not null,
+    public_key_pem text not null,
+    private_key_pem text not null,
+    primary key (user_id),
+    foreign key (user_id)
+    references users(id) on delete cascade
+);
+
+CREATE TABLE remote_actors (
+    id uuid not null,
+    uri text not null unique,
+    handle text not null,
+    inbox text not null,
+    shared_inbox text not null default '',
+    public_key_id text not null,
+    public_key_pem text not null,
+    fetched_at timestamp not null,
+    primary key (id)
+);
+
+CREATE INDEX remote_actors_key_idx ON remote_actors (public_key_id);
+
+CREATE TABLE remote_followers (
+    user_id uuid not null,
+    actor_id uuid not null,
+    follow_uri text not null,
+    created_at timestamp not null,
+    primary key (user_id, actor_id),
+    foreign key (user_id)
+    references users(id) on delete cascade,
+    foreign key (actor_id)
+    references remote_actors(id) on delete cascade
+);
+
+CREATE TABLE remote_follows (
+    user_id uuid not null,
+    actor_id uuid not null,
+    follow_uri text not null unique,
+    accepted boolean not null default false,
+    created_at timestamp not null,
+    primary key (user_id, actor_id),
+    foreign key (user_id)
+    references users(id) on delete cascade,
+    foreign key (actor_id)
+    references remote_actors(id) on delete cascade
+);
+
+CREATE TABLE deliveries (
+    id uuid not null,
+    created_at timestamp not null,
+    user_id uuid not null,
+    inbox text not null,
+    activity bytea not null,
+    attempts int not null default 0,
+    next_attempt_at timestamp not null,
+    last_error text not null default '',
+    primary key (id),
+    foreign key (user_id)
+    references users(id) on delete cascade
+);
+
+CREATE INDEX deliveries_due_idx ON deliveries (next_attempt_at);
+
+-- +goose Down
+DROP TABLE deliveries;
+
+DROP TABLE remote_follows;
+
+DROP TABLE remote_followers;
+
+DROP TABLE remote_actors;
+
+DROP TABLE actor_keys;
\ No newline at end of file
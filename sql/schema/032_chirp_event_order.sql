@@ -0,0 +1,38 @@
+-- +goose Up
+-- chirp_events ids are handed out when a row is inserted, but rows become
+-- visible when their transaction commits, so a reader that has seen id 10
+-- can still be missing id 9. seq is assigned at commit instead, under a
+-- lock held until the commit is visible, so it only ever grows.
+CREATE SEQUENCE chirp_events_seq;
+
+ALTER TABLE chirp_events ADD COLUMN seq bigint unique;
+
+UPDATE chirp_events SET seq = ordered.n
+FROM (select id, row_number() over (order by id) as n from chirp_events) ordered
+WHERE ordered.id = chirp_events.id;
+
+SELECT setval('chirp_events_seq', coalesce(max(seq), 0) + 1, false) FROM chirp_events;
+
+-- +goose StatementBegin
+CREATE FUNCTION sequence_chirp_event() RETURNS trigger
+LANGUAGE plpgsql AS $$
+BEGIN
+    PERFORM pg_advisory_xact_lock(hashtext('chirp_events_seq'));
+    UPDATE chirp_events SET seq = nextval('chirp_events_seq') WHERE id = NEW.id;
+    RETURN NULL;
+END;
+$$;
+-- +goose StatementEnd
+
+CREATE CONSTRAINT TRIGGER chirp_events_sequence AFTER INSERT ON chirp_events
+DEFERRABLE INITIALLY DEFERRED
+FOR EACH ROW EXECUTE FUNCTION sequence_chirp_event();
+
+-- +goose Down
+DROP TRIGGER chirp_events_sequence ON chirp_events;
+
+DROP FUNCTION sequence_chirp_event();
+
+ALTER TABLE chirp_events DROP COLUMN seq;
+
+DROP SEQUENCE chirp_events_seq;
\ No newline at end of file
@@ -0,0 +1,11 @@
+-- +goose Up
+-- A scheduled chirp that fails to publish is retried at retry_at, and
+-- goes back to the drafts after too many attempts.
+ALTER TABLE drafts ADD COLUMN attempts int not null default 0;
+
+ALTER TABLE drafts ADD COLUMN retry_at timestamp;
+
+-- +goose Down
+ALTER TABLE drafts DROP COLUMN retry_at;
+
+ALTER TABLE drafts DROP COLUMN attempts;
\ No newline at end of file
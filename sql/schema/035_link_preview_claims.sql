@@ -0,0 +1,8 @@
+-- +goose Up
+-- Claiming a link to fetch sets claimed_at rather than fetched_at, so a
+-- link isn't shown as fetched before it is, and one whose worker died is
+-- picked up again once the claim goes stale.
+ALTER TABLE link_previews ADD COLUMN claimed_at timestamp;
+
+-- +goose Down
+ALTER TABLE link_previews DROP COLUMN claimed_at;
\ No newline at end of file
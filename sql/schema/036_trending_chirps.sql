@@ -0,0 +1,20 @@
+-- +goose Up
+CREATE TABLE trending_chirps (
+    snapshot_id uuid not null,
+    position int not null,
+    chirp_id uuid not null,
+    score double precision not null,
+    primary key (snapshot_id, position),
+    foreign key (snapshot_id)
+    references trend_snapshots(id) on delete cascade,
+    foreign key (chirp_id)
+    references chirps(id) on delete cascade
+);
+
+-- Chirps are scored by the poll ballots cast in each window.
+CREATE INDEX poll_ballots_created_at_idx ON poll_ballots (created_at);
+
+-- +goose Down
+DROP INDEX poll_ballots_created_at_idx;
+
+DROP TABLE trending_chirps;
\ No newline at end of file
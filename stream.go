@@ -0,0 +1,213 @@
+package main
+
+import (
+	"chirpy/internal/database"
+	"chirpy/internal/entities"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	streamBuffer    = 64
+	streamHeartbeat = 30 * time.Second
+)
+
+// chirpStreamFilter is what a stream's client asked to see.
+type chirpStreamFilter struct {
+	viewer  uuid.UUID
+	author  uuid.NullUUID
+	hashtag string
+}
+
+// StreamChirps pushes chirp events as Server-Sent Events. Each event's id
+// is its chirp_events seq, which follows commit order, so a client that
+// reconnects with Last-Event-ID is first sent what it missed, provided
+// that's within chirpEventRetention.
+//
+// Created events carry the chirp, and only go to viewers who would see it
+// in GET /api/chirps. Deleted events carry just its id, and only go to
+// viewers who could see the chirp before it was deleted. ?author= limits
+// the stream to one user and ?hashtag= to one tag. A delete replayed after
+// the chirp was purged or restored isn't sent.
+func (cfg *apiConfig) StreamChirps(w http.ResponseWriter, r *http.Request) {
+	viewerUUID, err := cfg.viewer(r)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 401, res)
+		return
+	}
+	filter := chirpStreamFilter{viewer: viewerUUID}
+
+	if handle := r.URL.Query().Get("author"); handle != "" {
+		author, err := cfg.dbQueries.GetUserByHandle(r.Context(), handle)
+		if err != nil {
+			res := fmt.Sprintf(`{"error":"%v"}`, err)
+			formJsonResponse(w, 404, res)
+			return
+		}
+		filter.author = uuid.NullUUID{UUID: author.ID, Valid: true}
+	}
+	if tag := r.URL.Query().Get("hashtag"); tag != "" {
+		filter.hashtag = entities.NormalizeHashtag(strings.TrimPrefix(tag, "#"))
+	}
+
+	var last int64
+	if s := r.Header.Get("Last-Event-ID"); s != "" {
+		last, err = strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			res := `{"error":"Last-Event-ID must be an event id"}`
+			formJsonResponse(w, 400, res)
+			return
+		}
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		res := `{"error":"streaming is not supported"}`
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	// Subscribe before replaying so nothing falls between the two.
+	sub := cfg.chirpStream.Subscribe(streamBuffer)
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(200)
+	flusher.Flush()
+
+	for last > 0 {
+		events, err := cfg.dbQueries.GetChirpEventsAfter(r.Context(), database.GetChirpEventsAfterParams{
+			AfterSeq: last,
+			PageSize: chirpEventsReplayPage,
+		})
+		if err != nil {
+			return
+		}
+		for _, event := range events {
+			if err := cfg.sendChirpEvent(r.Context(), w, filter, event); err != nil {
+				return
+			}
+			last = event.Seq.Int64
+		}
+		flusher.Flush()
+		if len(events) < chirpEventsReplayPage {
+			break
+		}
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		case event, ok := <-sub.C:
+			// The broker drops streams that fall behind. Ending the
+			// response makes the client reconnect and resume.
+			if !ok {
+				return
+			}
+			if event.Seq.Int64 <= last {
+				continue
+			}
+			if err := cfg.sendChirpEvent(r.Context(), w, filter, event); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+// sendChirpEvent writes event to a stream if its filter lets it through.
+// The chirp comes from the chirpEventCache that WebSocket sessions share
+// too; each stream only checks whether its viewer gets the event.
+func (cfg *apiConfig) sendChirpEvent(ctx context.Context, w http.ResponseWriter, filter chirpStreamFilter, event database.ChirpEvent) error {
+	if filter.author.Valid && event.UserID != filter.author.UUID {
+		return nil
+	}
+
+	chirp, err := cfg.eventChirps.get(ctx, event)
+	if err != nil {
+		return err
+	}
+	if chirp == nil {
+		return nil
+	}
+
+	var data any
+	switch event.Kind {
+	case chirpEventCreated:
+		if filter.hashtag != "" && !hasHashtag(*chirp, filter.hashtag) {
+			return nil
+		}
+		visible, err := cfg.dbQueries.InChirpStream(ctx, database.InChirpStreamParams{
+			ID:       event.ChirpID,
+			ViewerID: filter.viewer,
+		})
+		if err != nil {
+			return err
+		}
+		if !visible {
+			return nil
+		}
+		data, err = cfg.withViewerPoll(ctx, *chirp, filter.viewer)
+		if err != nil {
+			return err
+		}
+	case chirpEventDeleted:
+		if filter.hashtag != "" && !bodyHasHashtag(chirp.Body, filter.hashtag) {
+			return nil
+		}
+		was, err := cfg.dbQueries.WasInChirpStream(ctx, database.WasInChirpStreamParams{
+			ID:       event.ChirpID,
+			ViewerID: filter.viewer,
+		})
+		if err != nil {
+			return err
+		}
+		if !was {
+			return nil
+		}
+		data = DeletedChirp{ID: event.ChirpID}
+	default:
+		return nil
+	}
+
+	jsr, err := json.Marshal(data)
+	if err != nil {
+		panic(err)
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.Seq.Int64, event.Kind, jsr)
+	return err
+}
+
+func bodyHasHashtag(body, tag string) bool {
+	for _, h := range entities.ParseHashtags(body) {
+		if h.Tag == tag {
+			return true
+		}
+	}
+	return false
+}
+
+func hasHashtag(chirp Chirp, tag string) bool {
+	for _, h := range chirp.Entities.Hashtags {
+		if h.Tag == tag {
+			return true
+		}
+	}
+	return false
+}
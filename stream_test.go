@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+type sseEvent struct {
+	id    string
+	event string
+	data  string
+}
+
+// readSSE passes on the events of a text/event-stream until it ends.
+func readSSE(r *bufio.Reader, events chan<- sseEvent) {
+	defer close(events)
+	var ev sseEvent
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\n")
+		switch {
+		case line == "":
+			if ev.event != "" {
+				events <- ev
+			}
+			ev = sseEvent{}
+		case strings.HasPrefix(line, "id: "):
+			ev.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			ev.event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			ev.data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+func nextSSE(t *testing.T, events <-chan sseEvent) sseEvent {
+	t.Helper()
+	select {
+	case ev, ok := <-events:
+		if !ok {
+			t.Fatal("the stream ended")
+		}
+		return ev
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event within 5s")
+	}
+	return sseEvent{}
+}
+
+func TestStreamChirpsSendsDeletes(t *testing.T) {
+	cfg := newTestConfig(t)
+	srv := newTestServer(t, cfg)
+	_, token := newTestUser(t, cfg, "author")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", srv.URL+"/api/stream/chirps", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	events := make(chan sseEvent, 8)
+	go readSSE(bufio.NewReader(resp.Body), events)
+
+	chirp := postChirp(t, srv, token, "going, going")
+	ev := nextSSE(t, events)
+	if ev.event != chirpEventCreated || !strings.Contains(ev.data, chirp.ID.String()) {
+		t.Fatalf("expected the created event, got %+v", ev)
+	}
+
+	deleteChirp(t, srv, token, chirp.ID)
+	ev = nextSSE(t, events)
+	if ev.event != chirpEventDeleted {
+		t.Fatalf("expected the deleted event, got %+v", ev)
+	}
+	var deleted DeletedChirp
+	if err := json.Unmarshal([]byte(ev.data), &deleted); err != nil {
+		t.Fatal(err)
+	}
+	if deleted.ID != chirp.ID {
+		t.Errorf("expected chirp %s to be deleted, got %s", chirp.ID, deleted.ID)
+	}
+
+	// The stream carries on after a delete.
+	next := postChirp(t, srv, token, "gone")
+	ev = nextSSE(t, events)
+	if ev.event != chirpEventCreated || !strings.Contains(ev.data, next.ID.String()) {
+		t.Fatalf("expected the stream to go on, got %+v", ev)
+	}
+}
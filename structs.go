@@ -7,18 +7,230 @@ import (
 )
 
 type User struct {
-	ID        uuid.UUID `json:"id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	Email     string    `json:"email"`
-	Token     string    `json:"token"`
-	Refresh   string    `json:"refresh_token"`
+	ID          uuid.UUID `json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	Email       string    `json:"email"`
+	Handle      string    `json:"handle"`
+	DisplayName string    `json:"display_name"`
+	Bio         string    `json:"bio"`
+	AvatarURL   string    `json:"avatar_url"`
+	Private     bool      `json:"private"`
+	Token       string    `json:"token"`
+	Refresh     string    `json:"refresh_token"`
+}
+
+// Profile is the public view of a user. It must never carry the email.
+type Profile struct {
+	ID          uuid.UUID `json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	Handle      string    `json:"handle"`
+	DisplayName string    `json:"display_name"`
+	Bio         string    `json:"bio"`
+	AvatarURL   string    `json:"avatar_url"`
+	Private     bool      `json:"private"`
+}
+
+type ProfileWithCounts struct {
+	Profile
+	FollowersCount int64 `json:"followers_count"`
+	FollowingCount int64 `json:"following_count"`
 }
 
 type Chirp struct {
-	ID        uuid.UUID `json:"id"`
+	ID             uuid.UUID     `json:"id"`
+	CreatedAt      time.Time     `json:"created_at"`
+	UpdatedAt      time.Time     `json:"updated_at"`
+	Body           string        `json:"body"`
+	User_id        uuid.UUID     `json:"user_id"`
+	Visibility     string        `json:"visibility"`
+	ContentWarning string        `json:"content_warning"`
+	Entities       ChirpEntities `json:"entities"`
+	Media          []Media       `json:"media"`
+	Poll           *Poll         `json:"poll"`
+	Pinned         bool          `json:"pinned"`
+}
+
+type ChirpEntities struct {
+	Mentions []MentionEntity `json:"mentions"`
+	Hashtags []HashtagEntity `json:"hashtags"`
+	Links    []LinkEntity    `json:"links"`
+}
+
+type MentionEntity struct {
+	UserID    uuid.UUID `json:"user_id"`
+	Handle    string    `json:"handle"`
+	ByteStart int32     `json:"byte_start"`
+	ByteEnd   int32     `json:"byte_end"`
+	CharStart int32     `json:"char_start"`
+	CharEnd   int32     `json:"char_end"`
+}
+
+type HashtagEntity struct {
+	Tag       string `json:"tag"`
+	ByteStart int32  `json:"byte_start"`
+	ByteEnd   int32  `json:"byte_end"`
+	CharStart int32  `json:"char_start"`
+	CharEnd   int32  `json:"char_end"`
+}
+
+// LinkEntity has a null preview until one has been fetched, and keeps it
+// null if the page couldn't be previewed.
+type LinkEntity struct {
+	URL       string       `json:"url"`
+	ByteStart int32        `json:"byte_start"`
+	ByteEnd   int32        `json:"byte_end"`
+	CharStart int32        `json:"char_start"`
+	CharEnd   int32        `json:"char_end"`
+	Preview   *LinkPreview `json:"preview"`
+}
+
+type LinkPreview struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	ImageURL    string `json:"image_url"`
+}
+
+type Trends struct {
+	Window      string            `json:"window"`
+	GeneratedAt time.Time         `json:"generated_at"`
+	Hashtags    []TrendingHashtag `json:"hashtags"`
+	Chirps      []TrendingChirp   `json:"chirps"`
+}
+
+type TrendingHashtag struct {
+	Tag   string  `json:"tag"`
+	Uses  int64   `json:"uses"`
+	Score float64 `json:"score"`
+}
+
+type TrendingChirp struct {
+	Chirp Chirp   `json:"chirp"`
+	Score float64 `json:"score"`
+}
+
+// DeletedChirp is the payload of a deleted event on a live stream.
+type DeletedChirp struct {
+	ID uuid.UUID `json:"id"`
+}
+
+type Notification struct {
+	ID        uuid.UUID  `json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	Kind      string     `json:"kind"`
+	Actor     Profile    `json:"actor"`
+	ChirpID   *uuid.UUID `json:"chirp_id"`
+}
+
+// NotificationGroup stands for one or more notifications of the same kind
+// about the same thing. ID and CreatedAt are the newest one's.
+type NotificationGroup struct {
+	ID              uuid.UUID   `json:"id"`
+	Kind            string      `json:"kind"`
+	ChirpID         *uuid.UUID  `json:"chirp_id"`
+	CreatedAt       time.Time   `json:"created_at"`
+	Actors          []Profile   `json:"actors"`
+	ActorsCount     int64       `json:"actors_count"`
+	Unread          bool        `json:"unread"`
+	NotificationIDs []uuid.UUID `json:"notification_ids"`
+}
+
+type Notifications struct {
+	UnreadCount int64               `json:"unread_count"`
+	Groups      []NotificationGroup `json:"groups"`
+}
+
+type Conversation struct {
+	ID          uuid.UUID            `json:"id"`
+	CreatedAt   time.Time            `json:"created_at"`
+	UpdatedAt   time.Time            `json:"updated_at"`
+	Members     []ConversationMember `json:"members"`
+	UnreadCount int64                `json:"unread_count"`
+}
+
+type ConversationMember struct {
+	Profile
+	LastReadAt time.Time `json:"last_read_at"`
+}
+
+type Message struct {
+	ID             uuid.UUID   `json:"id"`
+	CreatedAt      time.Time   `json:"created_at"`
+	ConversationID uuid.UUID   `json:"conversation_id"`
+	SenderID       uuid.UUID   `json:"sender_id"`
+	Body           string      `json:"body"`
+	ReadBy         []uuid.UUID `json:"read_by"`
+}
+
+type Media struct {
+	ID           uuid.UUID `json:"id"`
+	URL          string    `json:"url"`
+	ThumbnailURL string    `json:"thumbnail_url"`
+	ContentType  string    `json:"content_type"`
+	Width        int32     `json:"width"`
+	Height       int32     `json:"height"`
+}
+
+// Poll counts are null until the viewer has voted or the poll has closed.
+type Poll struct {
+	Multiple    bool         `json:"multiple"`
+	ClosesAt    time.Time    `json:"closes_at"`
+	Closed      bool         `json:"closed"`
+	VotersCount *int64       `json:"voters_count"`
+	Voted       bool         `json:"voted"`
+	OwnVotes    []int32      `json:"own_votes"`
+	Options     []PollOption `json:"options"`
+}
+
+type PollOption struct {
+	Title      string `json:"title"`
+	VotesCount *int64 `json:"votes_count"`
+}
+
+// Draft is a chirp that hasn't been published yet. One with a publish_at
+// is scheduled; error says why the scheduler last gave up on it.
+type Draft struct {
+	ID             uuid.UUID  `json:"id"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	Body           string     `json:"body"`
+	Visibility     string     `json:"visibility"`
+	ContentWarning string     `json:"content_warning"`
+	PublishAt      *time.Time `json:"publish_at"`
+	Error          string     `json:"error"`
+}
+
+type Collection struct {
+	ID             uuid.UUID `json:"id"`
+	CreatedAt      time.Time `json:"created_at"`
+	Name           string    `json:"name"`
+	BookmarksCount int64     `json:"bookmarks_count"`
+}
+
+// Bookmark has a null chirp when the chirp is gone or out of sight.
+type Bookmark struct {
+	ChirpID      uuid.UUID  `json:"chirp_id"`
+	CollectionID *uuid.UUID `json:"collection_id"`
+	CreatedAt    time.Time  `json:"created_at"`
+	Chirp        *Chirp     `json:"chirp"`
+}
+
+type List struct {
+	ID           uuid.UUID `json:"id"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	OwnerID      uuid.UUID `json:"owner_id"`
+	Name         string    `json:"name"`
+	Description  string    `json:"description"`
+	Private      bool      `json:"private"`
+	MembersCount int64     `json:"members_count"`
+}
+
+// RemoteFollow is an account on another server that a user follows.
+// Accepted stays false until that server confirms the follow.
+type RemoteFollow struct {
+	Account   string    `json:"account"`
+	URI       string    `json:"uri"`
+	Accepted  bool      `json:"accepted"`
 	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	Body      string    `json:"body"`
-	User_id   uuid.UUID `json:"user_id"`
 }
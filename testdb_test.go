@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"chirpy/internal/auth"
+	"chirpy/internal/database"
+	"chirpy/internal/pubsub"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// newTestConfig gives a test its own schema in the database at TEST_DB_URL,
+// with every migration applied, and an apiConfig using it. Tests that need
+// one are skipped when TEST_DB_URL isn't set.
+func newTestConfig(t *testing.T) *apiConfig {
+	t.Helper()
+	dbURL := os.Getenv("TEST_DB_URL")
+	if dbURL == "" {
+		t.Skip("TEST_DB_URL is not set")
+	}
+
+	admin, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema := "test_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+	if _, err := admin.Exec("create schema " + schema); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		admin.Exec("drop schema " + schema + " cascade")
+		admin.Close()
+	})
+
+	db, err := sql.Open("postgres", withSearchPath(dbURL, schema))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	migrate(t, db)
+
+	cfg := &apiConfig{
+		db:                 db,
+		dbQueries:          database.New(db),
+		Secret:             "test-secret",
+		chirpStream:        pubsub.New[database.ChirpEvent](),
+		notificationStream: pubsub.New[database.Notification](),
+	}
+	cfg.timelines = fanoutOnRead{dbQueries: cfg.dbQueries}
+	cfg.events = localEvents{cfg: cfg}
+	cfg.eventChirps = newChirpEventCache(cfg)
+	return cfg
+}
+
+// withSearchPath points connections at schema, keeping public for
+// extensions installed there.
+func withSearchPath(dbURL, schema string) string {
+	if strings.HasPrefix(dbURL, "postgres://") || strings.HasPrefix(dbURL, "postgresql://") {
+		u, err := url.Parse(dbURL)
+		if err == nil {
+			q := u.Query()
+			q.Set("search_path", schema+",public")
+			u.RawQuery = q.Encode()
+			return u.String()
+		}
+	}
+	return dbURL + " search_path=" + schema + ",public"
+}
+
+// migrate runs the Up half of every goose migration in order.
+func migrate(t *testing.T, db *sql.DB) {
+	t.Helper()
+	files, err := filepath.Glob("sql/schema/*.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		up := strings.ReplaceAll(string(data), "\r\n", "\n")
+		if i := strings.Index(up, "-- +goose Down"); i >= 0 {
+			up = up[:i]
+		}
+		if _, err := db.Exec(up); err != nil {
+			t.Fatalf("%s: %v", file, err)
+		}
+	}
+}
+
+// newTestUser creates a user with handle and returns them with a token.
+func newTestUser(t *testing.T, cfg *apiConfig, handle string) (database.User, string) {
+	t.Helper()
+	user, err := cfg.dbQueries.CreateUser(context.Background(), database.CreateUserParams{
+		Email:          handle + "@example.com",
+		HashedPassword: "unused",
+		Handle:         handle,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := auth.MakeJWT(user.ID, cfg.Secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user, token
+}
+
+// newTestServer serves the routes the stream tests drive.
+func newTestServer(t *testing.T, cfg *apiConfig) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/chirps", cfg.createChirp)
+	mux.HandleFunc("DELETE /api/chirps/{chirpid}", cfg.DeleteChirp)
+	mux.HandleFunc("GET /api/stream/chirps", cfg.StreamChirps)
+	mux.HandleFunc("GET /api/ws", cfg.Connect)
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func postChirp(t *testing.T, srv *httptest.Server, token, body string) Chirp {
+	t.Helper()
+	payload, _ := json.Marshal(map[string]string{"body": body})
+	req, _ := http.NewRequest("POST", srv.URL+"/api/chirps", bytes.NewReader(payload))
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 201 {
+		t.Fatalf("expected 201 creating a chirp, got %d", resp.StatusCode)
+	}
+	var chirp Chirp
+	if err := json.NewDecoder(resp.Body).Decode(&chirp); err != nil {
+		t.Fatal(err)
+	}
+	return chirp
+}
+
+func deleteChirp(t *testing.T, srv *httptest.Server, token string, id uuid.UUID) {
+	t.Helper()
+	req, _ := http.NewRequest("DELETE", srv.URL+"/api/chirps/"+id.String(), nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 204 {
+		t.Fatalf("expected 204 deleting a chirp, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"chirpy/internal/database"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// timelineStore builds home timelines. fanoutOnRead assembles them from the
+// follow graph when they are read. A fan-out-on-write store would instead
+// copy each new chirp into its author's followers' timelines from
+// ChirpCreated and backfill or trim them from FollowChanged; deleted chirps
+// would drop out through the foreign key on its timeline table.
+type timelineStore interface {
+	Home(ctx context.Context, userID uuid.UUID, after pageCursor, limit int32) ([]database.Chirp, error)
+	ChirpCreated(ctx context.Context, chirp database.Chirp) error
+	FollowChanged(ctx context.Context, followerID, followeeID uuid.UUID, following bool) error
+}
+
+type fanoutOnRead struct {
+	dbQueries *database.Queries
+}
+
+func (t fanoutOnRead) Home(ctx context.Context, userID uuid.UUID, after pageCursor, limit int32) ([]database.Chirp, error) {
+	return t.dbQueries.GetHomeTimeline(ctx, database.GetHomeTimelineParams{
+		ViewerID:        userID,
+		BeforeCreatedAt: after.CreatedAt,
+		BeforeID:        after.ID,
+		PageSize:        limit,
+	})
+}
+
+func (t fanoutOnRead) ChirpCreated(ctx context.Context, chirp database.Chirp) error {
+	return nil
+}
+
+func (t fanoutOnRead) FollowChanged(ctx context.Context, followerID, followeeID uuid.UUID, following bool) error {
+	return nil
+}
+
+func (cfg *apiConfig) HomeTimeline(w http.ResponseWriter, r *http.Request) {
+	userUUID, err := cfg.authenticate(r)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 401, res)
+		return
+	}
+
+	after, limit, err := parsePage(r, newestFirst)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 400, res)
+		return
+	}
+
+	dbChirps, err := cfg.timelines.Home(r.Context(), userUUID, after, limit)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+	chirps := []Chirp{}
+	for _, c := range dbChirps {
+		chirps = append(chirps, mapChirp(c))
+	}
+	err = cfg.attachEntities(r.Context(), chirps, userUUID)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+
+	if len(chirps) == int(limit) {
+		last := chirps[len(chirps)-1]
+		setNextPage(w, r, pageCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+	jsr, err := json.Marshal(chirps)
+	if err != nil {
+		panic(err)
+	}
+	respondWithJson(w, 200, jsr)
+}
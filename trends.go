@@ -0,0 +1,298 @@
+package main
+
+import (
+	"chirpy/internal/database"
+	"chirpy/internal/entities"
+	"chirpy/internal/httpcache"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// trendWindow is one of the periods trends are reported for. Activity in
+// the window counts half as much every HalfLife, so a tag picking up now
+// outranks one that had a burst at the start of the window.
+//
+// Hashtags are scored by the chirps that use them. Chirps are scored by
+// the votes their polls get from anyone but their author. Bookmarks are
+// private, so they don't count.
+type trendWindow struct {
+	Name     string
+	Length   time.Duration
+	HalfLife time.Duration
+}
+
+type trendsConfig struct {
+	Interval   time.Duration
+	MaxResults int32
+	Windows    []trendWindow
+}
+
+// trendsConfigFromEnv reads TRENDS_INTERVAL and the TRENDS_HALF_LIFE_1H,
+// _24H and _7D variables as Go durations, falling back to defaults when
+// they're unset.
+func trendsConfigFromEnv() (trendsConfig, error) {
+	tc := trendsConfig{
+		Interval:   5 * time.Minute,
+		MaxResults: 20,
+		Windows: []trendWindow{
+			{Name: "1h", Length: time.Hour, HalfLife: 15 * time.Minute},
+			{Name: "24h", Length: 24 * time.Hour, HalfLife: 6 * time.Hour},
+			{Name: "7d", Length: 7 * 24 * time.Hour, HalfLife: 48 * time.Hour},
+		},
+	}
+
+	var err error
+	tc.Interval, err = durationFromEnv("TRENDS_INTERVAL", tc.Interval)
+	if err != nil {
+		return tc, err
+	}
+	for i, window := range tc.Windows {
+		tc.Windows[i].HalfLife, err = durationFromEnv("TRENDS_HALF_LIFE_"+strings.ToUpper(window.Name), window.HalfLife)
+		if err != nil {
+			return tc, err
+		}
+	}
+	return tc, nil
+}
+
+// runTrendsWorker recomputes every window on a timer until ctx is done.
+func (cfg *apiConfig) runTrendsWorker(ctx context.Context) {
+	ticker := time.NewTicker(cfg.trends.Interval)
+	defer ticker.Stop()
+	for {
+		for _, window := range cfg.trends.Windows {
+			err := cfg.snapshotTrends(ctx, window)
+			if err != nil {
+				log.Printf("trends: %s window: %v", window.Name, err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// snapshotTrends writes a new snapshot for window and drops the ones it
+// replaces in the same transaction, so readers never see a partial list.
+func (cfg *apiConfig) snapshotTrends(ctx context.Context, window trendWindow) error {
+	scores, err := cfg.dbQueries.ScoreHashtags(ctx, database.ScoreHashtagsParams{
+		HalfLifeSeconds: window.HalfLife.Seconds(),
+		WindowSeconds:   window.Length.Seconds(),
+		MaxResults:      cfg.trends.MaxResults,
+	})
+	if err != nil {
+		return err
+	}
+	chirpScores, err := cfg.dbQueries.ScoreChirps(ctx, database.ScoreChirpsParams{
+		HalfLifeSeconds: window.HalfLife.Seconds(),
+		WindowSeconds:   window.Length.Seconds(),
+		MaxResults:      cfg.trends.MaxResults,
+	})
+	if err != nil {
+		return err
+	}
+
+	tx, err := cfg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	snapshot, err := qtx.CreateTrendSnapshot(ctx, window.Name)
+	if err != nil {
+		return err
+	}
+	for i, score := range scores {
+		err = qtx.CreateTrendingHashtag(ctx, database.CreateTrendingHashtagParams{
+			SnapshotID: snapshot.ID,
+			Position:   int32(i),
+			Tag:        score.Tag,
+			Uses:       score.Uses,
+			Score:      score.Score,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	for i, score := range chirpScores {
+		err = qtx.CreateTrendingChirp(ctx, database.CreateTrendingChirpParams{
+			SnapshotID: snapshot.ID,
+			Position:   int32(i),
+			ChirpID:    score.ChirpID,
+			Score:      score.Score,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	err = qtx.DeleteOlderTrendSnapshots(ctx, database.DeleteOlderTrendSnapshotsParams{
+		WindowName:  window.Name,
+		GeneratedAt: snapshot.GeneratedAt,
+	})
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetTrends serves the latest snapshot of each window, or only the one
+// named by ?window=. Trending chirps are filtered as the viewer may see
+// them now, so a chirp since hidden from them is left out.
+func (cfg *apiConfig) GetTrends(w http.ResponseWriter, r *http.Request) {
+	viewerUUID, err := cfg.viewer(r)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 401, res)
+		return
+	}
+	name := r.URL.Query().Get("window")
+
+	trends := []Trends{}
+	found := false
+	for _, window := range cfg.trends.Windows {
+		if name != "" && name != window.Name {
+			continue
+		}
+		found = true
+
+		snapshot, err := cfg.dbQueries.GetLatestTrendSnapshot(r.Context(), window.Name)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			res := fmt.Sprintf(`{"error":"%v"}`, err)
+			formJsonResponse(w, 500, res)
+			return
+		}
+
+		rows, err := cfg.dbQueries.GetTrendingHashtags(r.Context(), snapshot.ID)
+		if err != nil {
+			res := fmt.Sprintf(`{"error":"%v"}`, err)
+			formJsonResponse(w, 500, res)
+			return
+		}
+
+		chirpRows, err := cfg.dbQueries.GetTrendingChirps(r.Context(), database.GetTrendingChirpsParams{
+			SnapshotID: snapshot.ID,
+			ViewerID:   viewerUUID,
+		})
+		if err != nil {
+			res := fmt.Sprintf(`{"error":"%v"}`, err)
+			formJsonResponse(w, 500, res)
+			return
+		}
+		chirps := []Chirp{}
+		for _, row := range chirpRows {
+			chirps = append(chirps, mapChirp(row.Chirp))
+		}
+		err = cfg.attachEntities(r.Context(), chirps, viewerUUID)
+		if err != nil {
+			res := fmt.Sprintf(`{"error":"%v"}`, err)
+			formJsonResponse(w, 500, res)
+			return
+		}
+
+		t := Trends{
+			Window:      window.Name,
+			GeneratedAt: snapshot.GeneratedAt,
+			Hashtags:    []TrendingHashtag{},
+			Chirps:      []TrendingChirp{},
+		}
+		for _, row := range rows {
+			t.Hashtags = append(t.Hashtags, TrendingHashtag{
+				Tag:   row.Tag,
+				Uses:  row.Uses,
+				Score: row.Score,
+			})
+		}
+		for i, row := range chirpRows {
+			t.Chirps = append(t.Chirps, TrendingChirp{
+				Chirp: chirps[i],
+				Score: row.Score,
+			})
+		}
+		trends = append(trends, t)
+	}
+
+	if !found {
+		res := fmt.Sprintf(`{"error":"unknown window %v"}`, name)
+		formJsonResponse(w, 400, res)
+		return
+	}
+
+	jsr, err := json.Marshal(trends)
+	if err != nil {
+		panic(err)
+	}
+	respondWithJson(w, 200, jsr)
+}
+
+// hashtagChirps loads a page of the chirps tagged with tag, newest first,
+// as viewer may see them. It backs both the JSON list and the tag's feeds.
+func (cfg *apiConfig) hashtagChirps(ctx context.Context, tag string, viewer uuid.UUID, before pageCursor, limit int32) ([]Chirp, error) {
+	dbChirps, err := cfg.dbQueries.GetChirpsByHashtag(ctx, database.GetChirpsByHashtagParams{
+		Tag:             entities.NormalizeHashtag(tag),
+		ViewerID:        viewer,
+		BeforeCreatedAt: before.CreatedAt,
+		BeforeID:        before.ID,
+		PageSize:        limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	chirps := []Chirp{}
+	for _, c := range dbChirps {
+		chirps = append(chirps, mapChirp(c))
+	}
+	err = cfg.attachEntities(ctx, chirps, viewer)
+	if err != nil {
+		return nil, err
+	}
+	return chirps, nil
+}
+
+func (cfg *apiConfig) GetHashtagChirps(w http.ResponseWriter, r *http.Request) {
+	viewerUUID, err := cfg.viewer(r)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 401, res)
+		return
+	}
+
+	before, limit, err := parsePage(r, newestFirst)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 400, res)
+		return
+	}
+
+	chirps, err := cfg.hashtagChirps(r.Context(), r.PathValue("tag"), viewerUUID, before, limit)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 500, res)
+		return
+	}
+	if len(chirps) == int(limit) {
+		last := chirps[len(chirps)-1]
+		setNextPage(w, r, pageCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+
+	jsr, err := json.Marshal(chirps)
+	if err != nil {
+		panic(err)
+	}
+	httpcache.Write(w, r, "application/json", jsr, time.Time{})
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Who can see a chirp on top of what its author's account allows. Unlisted
+// chirps are visible to anyone with the link but left out of listings.
+const (
+	visibilityPublic    = "public"
+	visibilityFollowers = "followers"
+	visibilityMentioned = "mentioned"
+	visibilityUnlisted  = "unlisted"
+)
+
+const maxContentWarningLength = 100
+
+// validateChirpOptions fills in the default visibility and tidies the
+// content warning, rejecting values the database would refuse or that
+// can't mean anything.
+func validateChirpOptions(body, visibility, contentWarning string) (string, string, error) {
+	if visibility == "" {
+		visibility = visibilityPublic
+	}
+	switch visibility {
+	case visibilityPublic, visibilityFollowers, visibilityMentioned, visibilityUnlisted:
+	default:
+		return "", "", fmt.Errorf("visibility must be one of %s, %s, %s or %s", visibilityPublic, visibilityFollowers, visibilityMentioned, visibilityUnlisted)
+	}
+
+	contentWarning = strings.TrimSpace(contentWarning)
+	if utf8.RuneCountInString(contentWarning) > maxContentWarningLength {
+		return "", "", fmt.Errorf("content_warning must be at most %d characters", maxContentWarningLength)
+	}
+	if contentWarning != "" && strings.TrimSpace(body) == "" {
+		return "", "", errors.New("a content warning needs a body to hide")
+	}
+	return visibility, contentWarning, nil
+}
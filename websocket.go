@@ -0,0 +1,356 @@
+package main
+
+import (
+	"chirpy/internal/database"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+	"github.com/google/uuid"
+)
+
+const (
+	wsOutboxSize       = 64
+	wsReadLimit        = 4096
+	wsHeartbeat        = 30 * time.Second
+	wsWriteTimeout     = 10 * time.Second
+	wsMaxSubscriptions = 20
+)
+
+// Channels a WebSocket client can subscribe to.
+const (
+	wsChannelHome          = "home"
+	wsChannelNotifications = "notifications"
+	wsChannelThread        = "thread"
+)
+
+var errSlowConsumer = errors.New("client is too slow")
+
+// wsRequest is a frame from the client. ID names the subscription it's
+// about, so the client can tell events apart and unsubscribe later.
+type wsRequest struct {
+	Type    string    `json:"type"`
+	ID      string    `json:"id"`
+	Channel string    `json:"channel"`
+	ChirpID uuid.UUID `json:"chirp_id"`
+}
+
+type wsFrame struct {
+	Type  string `json:"type"`
+	ID    string `json:"id,omitempty"`
+	Event string `json:"event,omitempty"`
+	Data  any    `json:"data,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+type wsSubscription struct {
+	channel string
+	chirpID uuid.UUID
+}
+
+// wsSession is one client's connection. Its subscriptions are only touched
+// by the goroutine running Connect; writes go through outbox.
+type wsSession struct {
+	cfg    *apiConfig
+	conn   *websocket.Conn
+	userID uuid.UUID
+	subs   map[string]wsSubscription
+	outbox chan wsFrame
+}
+
+// Connect upgrades to a WebSocket that multiplexes live subscriptions over
+// JSON frames. It takes the same bearer JWT as the REST routes, sent on the
+// upgrade request.
+//
+// The client sends {"type":"subscribe","id":"h","channel":"home"} to start
+// a subscription, where channel is home, notifications, or thread along
+// with a chirp_id, and {"type":"unsubscribe","id":"h"} to end it. Replies
+// are "subscribed", "unsubscribed" or "error" frames with the same id, and
+// "event" frames carry the same events as the SSE stream. Chirpy has no
+// replies yet, so a thread is just its root chirp and the only event on it
+// is that chirp being deleted.
+//
+// A client that can't keep up is disconnected with status 1013 instead of
+// being buffered for without limit. It should reconnect and catch up over
+// REST.
+func (cfg *apiConfig) Connect(w http.ResponseWriter, r *http.Request) {
+	userUUID, err := cfg.authenticate(r)
+	if err != nil {
+		res := fmt.Sprintf(`{"error":"%v"}`, err)
+		formJsonResponse(w, 401, res)
+		return
+	}
+
+	// Accept writes its own response when the upgrade fails.
+	conn, err := websocket.Accept(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.CloseNow()
+	conn.SetReadLimit(wsReadLimit)
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	s := &wsSession{
+		cfg:    cfg,
+		conn:   conn,
+		userID: userUUID,
+		subs:   map[string]wsSubscription{},
+		outbox: make(chan wsFrame, wsOutboxSize),
+	}
+	chirpEvents := cfg.chirpStream.Subscribe(streamBuffer)
+	defer chirpEvents.Close()
+	notifications := cfg.notificationStream.Subscribe(streamBuffer)
+	defer notifications.Close()
+
+	requests := make(chan wsRequest)
+	go s.read(ctx, requests)
+	go s.write(ctx, cancel)
+	go s.heartbeat(ctx, cancel)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case req, ok := <-requests:
+			if !ok {
+				return
+			}
+			err = s.handle(ctx, req)
+		case event, ok := <-chirpEvents.C:
+			if !ok {
+				err = errSlowConsumer
+				break
+			}
+			err = s.chirpEvent(ctx, event)
+		case n, ok := <-notifications.C:
+			if !ok {
+				err = errSlowConsumer
+				break
+			}
+			err = s.notification(ctx, n)
+		}
+
+		if errors.Is(err, errSlowConsumer) {
+			conn.Close(websocket.StatusTryAgainLater, err.Error())
+			return
+		}
+		if err != nil {
+			conn.Close(websocket.StatusInternalError, "internal error")
+			return
+		}
+	}
+}
+
+// read passes the client's frames on until the connection fails, then
+// closes requests.
+func (s *wsSession) read(ctx context.Context, requests chan<- wsRequest) {
+	defer close(requests)
+	for {
+		var req wsRequest
+		err := wsjson.Read(ctx, s.conn, &req)
+		if err != nil {
+			return
+		}
+		select {
+		case requests <- req:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *wsSession) write(ctx context.Context, cancel context.CancelFunc) {
+	defer cancel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case frame := <-s.outbox:
+			wctx, wcancel := context.WithTimeout(ctx, wsWriteTimeout)
+			err := wsjson.Write(wctx, s.conn, frame)
+			wcancel()
+			if err != nil {
+				return
+			}
+		}
+	}
+}
+
+// heartbeat pings the client, ending the session when a pong doesn't come
+// back in time.
+func (s *wsSession) heartbeat(ctx context.Context, cancel context.CancelFunc) {
+	defer cancel()
+	ticker := time.NewTicker(wsHeartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			pctx, pcancel := context.WithTimeout(ctx, wsWriteTimeout)
+			err := s.conn.Ping(pctx)
+			pcancel()
+			if err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (s *wsSession) send(frame wsFrame) error {
+	select {
+	case s.outbox <- frame:
+		return nil
+	default:
+		return errSlowConsumer
+	}
+}
+
+func (s *wsSession) sendError(id, message string) error {
+	return s.send(wsFrame{Type: "error", ID: id, Error: message})
+}
+
+func (s *wsSession) handle(ctx context.Context, req wsRequest) error {
+	switch req.Type {
+	case "subscribe":
+		return s.subscribe(ctx, req)
+	case "unsubscribe":
+		if _, ok := s.subs[req.ID]; !ok {
+			return s.sendError(req.ID, "no such subscription")
+		}
+		delete(s.subs, req.ID)
+		return s.send(wsFrame{Type: "unsubscribed", ID: req.ID})
+	default:
+		return s.sendError(req.ID, fmt.Sprintf("unknown frame type %q", req.Type))
+	}
+}
+
+func (s *wsSession) subscribe(ctx context.Context, req wsRequest) error {
+	if req.ID == "" {
+		return s.sendError(req.ID, "a subscription needs an id")
+	}
+	if _, ok := s.subs[req.ID]; ok {
+		return s.sendError(req.ID, "subscription id is already in use")
+	}
+	if len(s.subs) >= wsMaxSubscriptions {
+		return s.sendError(req.ID, fmt.Sprintf("at most %d subscriptions per connection", wsMaxSubscriptions))
+	}
+
+	sub := wsSubscription{channel: req.Channel}
+	switch req.Channel {
+	case wsChannelHome, wsChannelNotifications:
+	case wsChannelThread:
+		_, err := s.cfg.dbQueries.GetChirp(ctx, database.GetChirpParams{
+			ID:       req.ChirpID,
+			ViewerID: s.userID,
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			return s.sendError(req.ID, "chirp not found")
+		}
+		if err != nil {
+			return err
+		}
+		sub.chirpID = req.ChirpID
+	default:
+		return s.sendError(req.ID, fmt.Sprintf("unknown channel %q", req.Channel))
+	}
+
+	s.subs[req.ID] = sub
+	return s.send(wsFrame{Type: "subscribed", ID: req.ID})
+}
+
+func (s *wsSession) chirpEvent(ctx context.Context, event database.ChirpEvent) error {
+	for id, sub := range s.subs {
+		data, err := s.chirpEventData(ctx, sub, event)
+		if err != nil {
+			return err
+		}
+		if data == nil {
+			continue
+		}
+		err = s.send(wsFrame{Type: "event", ID: id, Event: event.Kind, Data: data})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// chirpEventData is what sub should be sent for event, or nil if it isn't
+// meant for it. Whether a home subscription gets an event is one cheap
+// check per session; the chirp itself comes from the shared
+// chirpEventCache.
+func (s *wsSession) chirpEventData(ctx context.Context, sub wsSubscription, event database.ChirpEvent) (any, error) {
+	switch sub.channel {
+	case wsChannelHome:
+		if event.Kind == chirpEventDeleted {
+			was, err := s.cfg.dbQueries.WasInHomeTimeline(ctx, database.WasInHomeTimelineParams{
+				ID:       event.ChirpID,
+				ViewerID: s.userID,
+			})
+			if err != nil {
+				return nil, err
+			}
+			if !was {
+				return nil, nil
+			}
+			return DeletedChirp{ID: event.ChirpID}, nil
+		}
+
+		visible, err := s.cfg.dbQueries.InHomeTimeline(ctx, database.InHomeTimelineParams{
+			ID:       event.ChirpID,
+			ViewerID: s.userID,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if !visible {
+			return nil, nil
+		}
+		chirp, err := s.cfg.eventChirps.get(ctx, event)
+		if err != nil {
+			return nil, err
+		}
+		if chirp == nil {
+			return nil, nil
+		}
+		return s.cfg.withViewerPoll(ctx, *chirp, s.userID)
+	case wsChannelThread:
+		if event.Kind == chirpEventDeleted && event.ChirpID == sub.chirpID {
+			return DeletedChirp{ID: event.ChirpID}, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *wsSession) notification(ctx context.Context, n database.Notification) error {
+	if n.UserID != s.userID {
+		return nil
+	}
+
+	var data *Notification
+	for id, sub := range s.subs {
+		if sub.channel != wsChannelNotifications {
+			continue
+		}
+		if data == nil {
+			notification, err := s.cfg.mapNotification(ctx, n)
+			if err != nil {
+				return err
+			}
+			data = &notification
+		}
+		err := s.send(wsFrame{Type: "event", ID: id, Event: n.Kind, Data: data})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"chirpy/internal/database"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+)
+
+// wsEvent is a frame as the client sees it, with data left raw.
+type wsEvent struct {
+	Type  string          `json:"type"`
+	ID    string          `json:"id"`
+	Event string          `json:"event"`
+	Data  json.RawMessage `json:"data"`
+	Error string          `json:"error"`
+}
+
+func readWS(t *testing.T, ctx context.Context, conn *websocket.Conn) wsEvent {
+	t.Helper()
+	rctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	var frame wsEvent
+	if err := wsjson.Read(rctx, conn, &frame); err != nil {
+		t.Fatalf("reading a frame: %v", err)
+	}
+	return frame
+}
+
+func TestConnectHomeSendsDeletes(t *testing.T) {
+	cfg := newTestConfig(t)
+	srv := newTestServer(t, cfg)
+	author, authorToken := newTestUser(t, cfg, "author")
+	reader, readerToken := newTestUser(t, cfg, "reader")
+	_, err := cfg.dbQueries.FollowUser(context.Background(), database.FollowUserParams{
+		FollowerID: reader.ID,
+		FolloweeID: author.ID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	conn, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(srv.URL, "http")+"/api/ws", &websocket.DialOptions{
+		HTTPHeader: http.Header{"Authorization": {"Bearer " + readerToken}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.CloseNow()
+
+	err = wsjson.Write(ctx, conn, wsRequest{Type: "subscribe", ID: "h", Channel: wsChannelHome})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if frame := readWS(t, ctx, conn); frame.Type != "subscribed" {
+		t.Fatalf("expected to be subscribed, got %+v", frame)
+	}
+
+	chirp := postChirp(t, srv, authorToken, "going, going")
+	frame := readWS(t, ctx, conn)
+	if frame.Type != "event" || frame.Event != chirpEventCreated || !strings.Contains(string(frame.Data), chirp.ID.String()) {
+		t.Fatalf("expected the created event, got %+v", frame)
+	}
+
+	deleteChirp(t, srv, authorToken, chirp.ID)
+	frame = readWS(t, ctx, conn)
+	if frame.Type != "event" || frame.ID != "h" || frame.Event != chirpEventDeleted {
+		t.Fatalf("expected the deleted event, got %+v", frame)
+	}
+	var deleted DeletedChirp
+	if err := json.Unmarshal(frame.Data, &deleted); err != nil {
+		t.Fatal(err)
+	}
+	if deleted.ID != chirp.ID {
+		t.Errorf("expected chirp %s to be deleted, got %s", chirp.ID, deleted.ID)
+	}
+
+	// The session stays open after a delete.
+	next := postChirp(t, srv, authorToken, "gone")
+	frame = readWS(t, ctx, conn)
+	if frame.Event != chirpEventCreated || !strings.Contains(string(frame.Data), next.ID.String()) {
+		t.Fatalf("expected the session to go on, got %+v", frame)
+	}
+}